@@ -0,0 +1,75 @@
+package dktest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// imageArchive opens the image archive specified by the Options
+func imageArchive(opts Options) (io.ReadCloser, error) {
+	switch {
+	case opts.ImageArchive != "" && opts.ImageArchiveReader != nil:
+		return nil, errImageArchive
+	case opts.ImageArchiveReader != nil:
+		return io.NopCloser(opts.ImageArchiveReader), nil
+	default:
+		return os.Open(opts.ImageArchive)
+	}
+}
+
+// loadImage loads the images in the archive created by `docker save` and verifies that the image was loaded
+func loadImage(ctx context.Context, lgr Logger, dc ImageAPIClient, imgName string, opts Options) error {
+	archive, err := imageArchive(opts)
+	if err != nil {
+		return err
+	}
+	defer archive.Close() // nolint:errcheck
+
+	lgr.Log("Loading image:", imgName)
+	resp, err := dc.ImageLoad(ctx, archive, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			lgr.Log("Failed to close image load response:", err)
+		}
+	}()
+
+	output := strings.Builder{}
+	if resp.JSON {
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var msg jsonmessage.JSONMessage
+			if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return err
+			}
+			if msg.Error != nil {
+				return msg.Error
+			} else if msg.ErrorMessage != "" {
+				return errors.New(msg.ErrorMessage)
+			}
+			output.WriteString(msg.Stream)
+		}
+	} else if _, err := io.Copy(&output, resp.Body); err != nil {
+		return err
+	}
+	lgr.Log("Image load output:", output.String())
+
+	if _, err := dc.ImageInspect(ctx, imgName); err != nil {
+		return fmt.Errorf("%w: %v error: %w", errImageNotLoaded, imgName, err)
+	}
+	lgr.Log("Loaded image:", imgName)
+
+	return nil
+}
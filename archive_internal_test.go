@@ -0,0 +1,78 @@
+package dktest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/image"
+)
+
+func TestLoadImage(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(archivePath, []byte("archive"), 0o600); err != nil {
+		t.Fatal("Error writing image archive:", err)
+	}
+
+	successResp := `{"stream":"Loaded image: dktestFakeImageName:latest\n"}`
+	errorResp := `{"errorDetail":{"message":"unexpected EOF"},"error":"unexpected EOF"}`
+	successInspectResp := &image.InspectResponse{}
+
+	testCases := []struct {
+		name        string
+		client      mockdockerclient.ImageAPIClient
+		opts        Options
+		expectedErr error
+		expectErr   bool
+	}{
+		{name: "success - path", client: mockdockerclient.ImageAPIClient{
+			LoadResp: io.NopCloser(strings.NewReader(successResp)), InspectResp: successInspectResp},
+			opts: Options{ImageArchive: archivePath}, expectErr: false},
+		{name: "success - reader", client: mockdockerclient.ImageAPIClient{
+			LoadResp: io.NopCloser(strings.NewReader(successResp)), InspectResp: successInspectResp},
+			opts: Options{ImageArchiveReader: strings.NewReader("archive")}, expectErr: false},
+		{name: "path and reader", client: mockdockerclient.ImageAPIClient{
+			LoadResp: io.NopCloser(strings.NewReader(successResp)), InspectResp: successInspectResp},
+			opts:        Options{ImageArchive: archivePath, ImageArchiveReader: strings.NewReader("archive")},
+			expectedErr: errImageArchive, expectErr: true},
+		{name: "missing archive", client: mockdockerclient.ImageAPIClient{
+			LoadResp: io.NopCloser(strings.NewReader(successResp)), InspectResp: successInspectResp},
+			opts: Options{ImageArchive: filepath.Join(t.TempDir(), "missing.tar")}, expectErr: true},
+		{name: "load error", client: mockdockerclient.ImageAPIClient{InspectResp: successInspectResp},
+			opts: Options{ImageArchive: archivePath}, expectErr: true},
+		{name: "load output error", client: mockdockerclient.ImageAPIClient{
+			LoadResp: io.NopCloser(strings.NewReader(errorResp)), InspectResp: successInspectResp},
+			opts: Options{ImageArchive: archivePath}, expectErr: true},
+		{name: "load output error message", client: mockdockerclient.ImageAPIClient{
+			LoadResp: io.NopCloser(strings.NewReader(`{"error":"unexpected EOF"}`)), InspectResp: successInspectResp},
+			opts: Options{ImageArchive: archivePath}, expectErr: true},
+		{name: "malformed load output", client: mockdockerclient.ImageAPIClient{
+			LoadResp: io.NopCloser(strings.NewReader("{")), InspectResp: successInspectResp},
+			opts: Options{ImageArchive: archivePath}, expectErr: true},
+		{name: "image not in archive", client: mockdockerclient.ImageAPIClient{
+			LoadResp: io.NopCloser(strings.NewReader(successResp)), InspectErr: mockdockerclient.Err},
+			opts: Options{ImageArchive: archivePath}, expectedErr: errImageNotLoaded, expectErr: true},
+		{name: "close error", client: mockdockerclient.ImageAPIClient{
+			LoadResp: mockdockerclient.MockReadCloser{
+				MockReader: mockdockerclient.MockReader{Err: io.EOF},
+				MockCloser: mockdockerclient.MockCloser{Err: mockdockerclient.Err},
+			}, InspectResp: successInspectResp}, opts: Options{ImageArchive: archivePath}, expectErr: false},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			err := loadImage(ctx, t, &client, imageName, tc.opts)
+			testErr(t, err, tc.expectErr)
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error: %v but got: %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package dktest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+const (
+	dockerConfigEnvVar = "DOCKER_CONFIG"
+	dockerConfigFile   = "config.json"
+	// dockerHubServer is the server address used by the Docker CLI to store Docker Hub credentials
+	dockerHubServer = "https://index.docker.io/v1/"
+	// credentialHelperPrefix is the prefix of the credential helper executables
+	credentialHelperPrefix = "docker-credential-"
+	// credentialHelperTokenUsername is the username returned by credential helpers for identity tokens
+	credentialHelperTokenUsername = "<token>"
+	// credentialHelperNotFound is the error returned by credential helpers when there are no credentials for a server
+	credentialHelperNotFound = "credentials not found in native keychain"
+)
+
+// dockerConfig is the subset of the Docker CLI's config file used to resolve registry credentials
+type dockerConfig struct {
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+	CredsStore  string                `json:"credsStore"`
+}
+
+type dockerAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+// credentialHelperOutput is the output of a credential helper's "get" command
+type credentialHelperOutput struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// dockerConfigPath gets the path of the Docker CLI's config file
+func dockerConfigPath() (string, error) {
+	if dir := os.Getenv(dockerConfigEnvVar); dir != "" {
+		return filepath.Join(dir, dockerConfigFile), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker", dockerConfigFile), nil
+}
+
+// loadDockerConfig loads the Docker CLI's config file. A missing config file is treated as an empty config.
+func loadDockerConfig() (dockerConfig, error) {
+	var cfg dockerConfig
+	p, err := dockerConfigPath()
+	if err != nil {
+		return cfg, err
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	} else if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing Docker config: %v error: %w", p, err)
+	}
+	return cfg, nil
+}
+
+// registryHost gets the host of the registry the image is pulled from. e.g. "docker.io" or "ghcr.io"
+func registryHost(imgName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imgName)
+	if err != nil {
+		return "", err
+	}
+	return reference.Domain(named), nil
+}
+
+// serverAddress gets the server address the Docker CLI uses for the registry host
+func serverAddress(host string) string {
+	if host == "docker.io" {
+		return dockerHubServer
+	}
+	return host
+}
+
+// configHost normalizes the keys of the config file's auths, which may be URLs. e.g. "https://ghcr.io/v1/"
+func configHost(key string) string {
+	key = strings.TrimPrefix(key, "http://")
+	key = strings.TrimPrefix(key, "https://")
+	host, _, _ := strings.Cut(key, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return host
+}
+
+// authConfig gets the registry credentials stored in the config file's auths
+func (cfg dockerConfig) authConfig(host string) (registry.AuthConfig, bool, error) {
+	auth, ok := cfg.Auths[serverAddress(host)]
+	if !ok {
+		for key, a := range cfg.Auths {
+			if configHost(key) == host {
+				auth, ok = a, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return registry.AuthConfig{}, false, nil
+	}
+
+	authConfig := registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ServerAddress: serverAddress(host),
+		IdentityToken: auth.IdentityToken,
+		RegistryToken: auth.RegistryToken,
+	}
+	if auth.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return registry.AuthConfig{}, false, fmt.Errorf("error decoding auth for registry: %v error: %w", host,
+				err)
+		}
+		username, password, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return registry.AuthConfig{}, false, fmt.Errorf("invalid auth for registry: %v", host)
+		}
+		authConfig.Username, authConfig.Password = username, password
+	}
+	if authConfig == (registry.AuthConfig{ServerAddress: authConfig.ServerAddress}) {
+		// Entries without credentials are placeholders for credentials stored by the credsStore
+		return registry.AuthConfig{}, false, nil
+	}
+	return authConfig, true, nil
+}
+
+// credentialHelper gets the credential helper for the registry host. Same as the Docker CLI, the "credHelpers" are keyed
+// by the server address, which is "https://index.docker.io/v1/" for Docker Hub, but keys that are URLs or aliases of
+// the registry host are also matched. The "credsStore" is used if no credential helper is configured for the host.
+func (cfg dockerConfig) credentialHelper(host string) string {
+	if helper, ok := cfg.CredHelpers[serverAddress(host)]; ok {
+		return helper
+	}
+	for key, helper := range cfg.CredHelpers {
+		if configHost(key) == host {
+			return helper
+		}
+	}
+	return cfg.CredsStore
+}
+
+// credentialHelperAuthConfig gets the registry credentials stored by the credential helper
+func credentialHelperAuthConfig(ctx context.Context, helper, host string) (registry.AuthConfig, bool, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, credentialHelperPrefix+helper, "get") // nolint:gosec
+	cmd.Stdin = strings.NewReader(serverAddress(host))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		output := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(output, credentialHelperNotFound) {
+			return registry.AuthConfig{}, false, nil
+		}
+		return registry.AuthConfig{}, false, fmt.Errorf("error running credential helper: %v error: %w output: %v",
+			helper, err, output)
+	}
+
+	var out credentialHelperOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return registry.AuthConfig{}, false, fmt.Errorf("error parsing credential helper output: %v error: %w",
+			helper, err)
+	}
+	authConfig := registry.AuthConfig{ServerAddress: serverAddress(host)}
+	if out.Username == credentialHelperTokenUsername {
+		authConfig.IdentityToken = out.Secret
+	} else {
+		authConfig.Username, authConfig.Password = out.Username, out.Secret
+	}
+	return authConfig, true, nil
+}
+
+// resolveRegistryAuth resolves the base64 encoded registry credentials for the image using the Docker CLI's config.
+// Credential helpers configured for the registry in "credHelpers" are used first, followed by the "credsStore" and
+// finally the "auths". An empty string is returned if there are no credentials for the image's registry.
+func resolveRegistryAuth(ctx context.Context, imgName string) (string, error) {
+	host, err := registryHost(imgName)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := loadDockerConfig()
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		authConfig registry.AuthConfig
+		ok         bool
+	)
+	if helper := cfg.credentialHelper(host); helper != "" {
+		if authConfig, ok, err = credentialHelperAuthConfig(ctx, helper, host); err != nil {
+			return "", err
+		}
+	}
+	if !ok {
+		if authConfig, ok, err = cfg.authConfig(host); err != nil {
+			return "", err
+		}
+	}
+	if !ok {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(authConfig)
+}
+
+// registryAuth gets the registry credentials used to pull the image. The PullRegistryAuth Options are used if
+// specified. Otherwise, the credentials are resolved from the Docker CLI's config. Failing to resolve the credentials
+// isn't an error since public images can be pulled without credentials.
+func registryAuth(ctx context.Context, lgr Logger, imgName string, opts Options) string {
+	if opts.PullRegistryAuth != "" {
+		return opts.PullRegistryAuth
+	}
+	auth, err := resolveRegistryAuth(ctx, imgName)
+	if err != nil {
+		lgr.Log("Failed to resolve registry credentials for image:", imgName, "error:", err)
+		return ""
+	}
+	if auth != "" {
+		lgr.Log("Using registry credentials from Docker config for image:", imgName)
+	}
+	return auth
+}
@@ -0,0 +1,177 @@
+package dktest
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCredentialHelper is a credential helper that has credentials for Docker Hub and ghcr.io and a token for quay.io
+const fakeCredentialHelper = `#!/bin/sh
+read server
+case "$server" in
+https://index.docker.io/v1/) echo '{"ServerURL":"https://index.docker.io/v1/","Username":"hubuser","Secret":"hubpass"}' ;;
+ghcr.io) echo '{"ServerURL":"ghcr.io","Username":"helperuser","Secret":"helperpass"}' ;;
+quay.io) echo '{"ServerURL":"quay.io","Username":"<token>","Secret":"helpertoken"}' ;;
+broken.io) echo 'broken' ;;
+*) echo 'credentials not found in native keychain'; exit 1 ;;
+esac
+`
+
+func writeDockerConfig(t *testing.T, config string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, dockerConfigFile), []byte(config), 0o600); err != nil {
+		t.Fatal("Error writing Docker config:", err)
+	}
+	t.Setenv(dockerConfigEnvVar, dir)
+}
+
+func installCredentialHelper(t *testing.T, name string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Credential helper script requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	helper := filepath.Join(dir, credentialHelperPrefix+name)
+	if err := os.WriteFile(helper, []byte(fakeCredentialHelper), 0o700); err != nil { // nolint:gosec
+		t.Fatal("Error writing credential helper:", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func encodeAuth(t *testing.T, authConfig registry.AuthConfig) string {
+	t.Helper()
+	auth, err := registry.EncodeAuthConfig(authConfig)
+	if err != nil {
+		t.Fatal("Error encoding auth config:", err)
+	}
+	return auth
+}
+
+func TestRegistryHost(t *testing.T) {
+	testCases := []struct {
+		imgName   string
+		expected  string
+		expectErr bool
+	}{
+		{imgName: "postgres:16-alpine", expected: "docker.io", expectErr: false},
+		{imgName: "dhui/dktest", expected: "docker.io", expectErr: false},
+		{imgName: "ghcr.io/dhui/dktest:latest", expected: "ghcr.io", expectErr: false},
+		{imgName: "localhost:5000/dktest", expected: "localhost:5000", expectErr: false},
+		{imgName: "INVALID", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.imgName, func(t *testing.T) {
+			host, err := registryHost(tc.imgName)
+			testErr(t, err, tc.expectErr)
+			assert.Equal(t, tc.expected, host)
+		})
+	}
+}
+
+func TestResolveRegistryAuth(t *testing.T) {
+	basicAuth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+
+	testCases := []struct {
+		name      string
+		config    string
+		imgName   string
+		expected  registry.AuthConfig
+		noAuth    bool
+		expectErr bool
+	}{
+		{name: "no config", imgName: "postgres", noAuth: true, expectErr: false},
+		{name: "malformed config", config: "{", imgName: "postgres", expectErr: true},
+		{name: "docker hub auths", config: `{"auths":{"https://index.docker.io/v1/":{"auth":"` + basicAuth + `"}}}`,
+			imgName: "postgres", expected: registry.AuthConfig{Username: "user", Password: "pass",
+				ServerAddress: dockerHubServer}, expectErr: false},
+		{name: "auths url", config: `{"auths":{"https://ghcr.io/v1/":{"auth":"` + basicAuth + `"}}}`,
+			imgName: "ghcr.io/dhui/dktest", expected: registry.AuthConfig{Username: "user", Password: "pass",
+				ServerAddress: "ghcr.io"}, expectErr: false},
+		{name: "auths identity token", config: `{"auths":{"ghcr.io":{"identitytoken":"token"}}}`,
+			imgName: "ghcr.io/dhui/dktest", expected: registry.AuthConfig{IdentityToken: "token",
+				ServerAddress: "ghcr.io"}, expectErr: false},
+		{name: "auths other registry", config: `{"auths":{"ghcr.io":{"auth":"` + basicAuth + `"}}}`,
+			imgName: "postgres", noAuth: true, expectErr: false},
+		{name: "auths invalid base64", config: `{"auths":{"ghcr.io":{"auth":"!"}}}`,
+			imgName: "ghcr.io/dhui/dktest", expectErr: true},
+		{name: "auths missing password", config: `{"auths":{"ghcr.io":{"auth":"` +
+			base64.StdEncoding.EncodeToString([]byte("user")) + `"}}}`, imgName: "ghcr.io/dhui/dktest",
+			expectErr: true},
+		{name: "cred helpers", config: `{"credHelpers":{"ghcr.io":"fake"}}`, imgName: "ghcr.io/dhui/dktest",
+			expected: registry.AuthConfig{Username: "helperuser", Password: "helperpass",
+				ServerAddress: "ghcr.io"}, expectErr: false},
+		{name: "cred helpers docker hub", config: `{"credHelpers":{"https://index.docker.io/v1/":"fake"}}`,
+			imgName: "postgres", expected: registry.AuthConfig{Username: "hubuser", Password: "hubpass",
+				ServerAddress: dockerHubServer}, expectErr: false},
+		{name: "cred helpers docker hub host", config: `{"credHelpers":{"index.docker.io":"fake"}}`,
+			imgName: "docker.io/library/postgres", expected: registry.AuthConfig{Username: "hubuser",
+				Password: "hubpass", ServerAddress: dockerHubServer}, expectErr: false},
+		{name: "cred helpers identity token", config: `{"credHelpers":{"quay.io":"fake"}}`,
+			imgName: "quay.io/dhui/dktest", expected: registry.AuthConfig{IdentityToken: "helpertoken",
+				ServerAddress: "quay.io"}, expectErr: false},
+		{name: "cred helpers win over auths", config: `{"auths":{"ghcr.io":{"auth":"` + basicAuth +
+			`"}},"credHelpers":{"ghcr.io":"fake"}}`, imgName: "ghcr.io/dhui/dktest",
+			expected: registry.AuthConfig{Username: "helperuser", Password: "helperpass",
+				ServerAddress: "ghcr.io"}, expectErr: false},
+		{name: "creds store", config: `{"auths":{"ghcr.io":{}},"credsStore":"fake"}`,
+			imgName: "ghcr.io/dhui/dktest", expected: registry.AuthConfig{Username: "helperuser",
+				Password: "helperpass", ServerAddress: "ghcr.io"}, expectErr: false},
+		{name: "creds store not found", config: `{"credsStore":"fake"}`, imgName: "example.io/dhui/dktest",
+			noAuth: true, expectErr: false},
+		{name: "creds store not found falls back to auths", config: `{"auths":{"example.io":{"auth":"` +
+			basicAuth + `"}},"credsStore":"fake"}`, imgName: "example.io/dhui/dktest", expected: registry.AuthConfig{
+			Username: "user", Password: "pass", ServerAddress: "example.io"}, expectErr: false},
+		{name: "malformed helper output", config: `{"credsStore":"fake"}`, imgName: "broken.io/dhui/dktest",
+			expectErr: true},
+		{name: "missing helper", config: `{"credsStore":"missing"}`, imgName: "postgres", expectErr: true},
+		{name: "invalid image", config: `{}`, imgName: "INVALID", expectErr: true},
+	}
+
+	installCredentialHelper(t, "fake")
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.config == "" {
+				t.Setenv(dockerConfigEnvVar, t.TempDir())
+			} else {
+				writeDockerConfig(t, tc.config)
+			}
+			auth, err := resolveRegistryAuth(ctx, tc.imgName)
+			testErr(t, err, tc.expectErr)
+			if tc.expectErr {
+				return
+			}
+			if tc.noAuth {
+				assert.Empty(t, auth)
+				return
+			}
+			assert.Equal(t, encodeAuth(t, tc.expected), auth)
+		})
+	}
+}
+
+func TestRegistryAuth(t *testing.T) {
+	writeDockerConfig(t, `{"auths":{"https://index.docker.io/v1/":{"username":"user","password":"pass"}}}`)
+	ctx := context.Background()
+
+	t.Run("explicit", func(t *testing.T) {
+		assert.Equal(t, "explicit", registryAuth(ctx, t, "postgres", Options{PullRegistryAuth: "explicit"}))
+	})
+	t.Run("resolved", func(t *testing.T) {
+		expected := encodeAuth(t, registry.AuthConfig{Username: "user", Password: "pass",
+			ServerAddress: dockerHubServer})
+		assert.Equal(t, expected, registryAuth(ctx, t, "postgres", Options{}))
+	})
+	t.Run("resolve error", func(t *testing.T) {
+		assert.Empty(t, registryAuth(ctx, t, "INVALID", Options{}))
+	})
+}
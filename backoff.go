@@ -0,0 +1,46 @@
+package dktest
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultBackoffMultiplier = 2
+)
+
+// Backoff is an exponential backoff policy for the interval between container ready checks
+type Backoff struct {
+	// Multiplier is the factor the interval is multiplied by after each ready check. Defaults to 2
+	Multiplier float64
+	// MaxInterval is the maximum interval between ready checks. The interval isn't capped if not specified.
+	MaxInterval time.Duration
+	// Jitter is the fraction of each interval that's randomly added or subtracted. e.g. 0.1 for +/- 10%
+	// Jitter prevents parallel tests from checking if their containers are ready in lockstep.
+	Jitter float64
+}
+
+// next gets the interval to use after the given interval
+func (b *Backoff) next(interval time.Duration) time.Duration {
+	if b == nil {
+		return interval
+	}
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultBackoffMultiplier
+	}
+	next := time.Duration(float64(interval) * multiplier)
+	if b.MaxInterval > 0 && next > b.MaxInterval {
+		next = b.MaxInterval
+	}
+	return next
+}
+
+// jitter randomizes the given interval by the Backoff's Jitter
+func (b *Backoff) jitter(interval time.Duration) time.Duration {
+	if b == nil || b.Jitter <= 0 {
+		return interval
+	}
+	delta := b.Jitter * float64(interval)
+	return interval + time.Duration(delta*(2*rand.Float64()-1)) // nolint:gosec
+}
@@ -0,0 +1,59 @@
+package dktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffNext(t *testing.T) {
+	testCases := []struct {
+		name     string
+		backoff  *Backoff
+		interval time.Duration
+		expected time.Duration
+	}{
+		{name: "nil", backoff: nil, interval: time.Second, expected: time.Second},
+		{name: "default multiplier", backoff: &Backoff{}, interval: time.Second, expected: 2 * time.Second},
+		{name: "multiplier", backoff: &Backoff{Multiplier: 1.5}, interval: time.Second,
+			expected: 1500 * time.Millisecond},
+		{name: "max interval", backoff: &Backoff{MaxInterval: 3 * time.Second}, interval: 2 * time.Second,
+			expected: 3 * time.Second},
+		{name: "under max interval", backoff: &Backoff{MaxInterval: 3 * time.Second}, interval: time.Second,
+			expected: 2 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if next := tc.backoff.next(tc.interval); next != tc.expected {
+				t.Error("Next interval does not match expected:", next, "!=", tc.expected)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	interval := time.Second
+
+	t.Run("nil", func(t *testing.T) {
+		var b *Backoff
+		if j := b.jitter(interval); j != interval {
+			t.Error("Jittered interval does not match expected:", j, "!=", interval)
+		}
+	})
+
+	t.Run("no jitter", func(t *testing.T) {
+		b := &Backoff{}
+		if j := b.jitter(interval); j != interval {
+			t.Error("Jittered interval does not match expected:", j, "!=", interval)
+		}
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		b := &Backoff{Jitter: 0.1}
+		for i := 0; i < 100; i++ {
+			if j := b.jitter(interval); j < 900*time.Millisecond || j > 1100*time.Millisecond {
+				t.Fatal("Jittered interval out of range:", j)
+			}
+		}
+	})
+}
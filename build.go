@@ -0,0 +1,223 @@
+package dktest
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+const (
+	buildTagPrefix         = "dktest_"
+	defaultBuildRepository = "dktest"
+	defaultDockerfile      = "Dockerfile"
+	dockerignoreFile       = ".dockerignore"
+)
+
+// BuildOptions contains the configurable options for building a Docker image.
+// Exactly one of ContextDir or ContextFS must be specified.
+// The files excluded by the .dockerignore file in the root of the build context aren't sent to the Docker daemon.
+// Symlinks are sent as symlinks. Symlinks in a ContextFS are only supported if the ContextFS has a
+// ReadLink(name string) (string, error) method. Sockets, named pipes, and devices aren't sent.
+type BuildOptions struct {
+	// ContextDir is the path of the build context directory on the host
+	ContextDir string
+	// ContextFS is the build context
+	ContextFS fs.FS
+	// Dockerfile is the path of the Dockerfile within the build context. Defaults to "Dockerfile"
+	Dockerfile string
+	// BuildArgs are the build-time variables. e.g. --build-arg
+	BuildArgs map[string]*string
+	// Target is the build stage to build. e.g. --target
+	Target string
+}
+
+func (b *BuildOptions) contextFS() (fs.FS, error) {
+	switch {
+	case b.ContextDir != "" && b.ContextFS != nil:
+		return nil, errBuildContext
+	case b.ContextDir != "":
+		return os.DirFS(b.ContextDir), nil
+	case b.ContextFS != nil:
+		return b.ContextFS, nil
+	default:
+		return nil, errBuildContext
+	}
+}
+
+// readLink gets the target of the symlink in the build context
+func (b *BuildOptions) readLink(fsys fs.FS, p string) (string, error) {
+	if b.ContextDir != "" {
+		return os.Readlink(filepath.Join(b.ContextDir, filepath.FromSlash(p)))
+	}
+	if l, ok := fsys.(interface{ ReadLink(string) (string, error) }); ok {
+		return l.ReadLink(p)
+	}
+	return "", fmt.Errorf("%w: %q is a symlink and the ContextFS doesn't support ReadLink", errFileType, p)
+}
+
+// excludes gets the matcher for the files excluded by the build context's .dockerignore file. Same as the Docker CLI,
+// the Dockerfile and the .dockerignore file are always sent since the Docker daemon needs them.
+func (b *BuildOptions) excludes(fsys fs.FS) (*patternmatcher.PatternMatcher, error) {
+	f, err := fsys.Open(dockerignoreFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return patternmatcher.New(nil)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint:errcheck
+	patterns, err := ignorefile.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v error: %w", dockerignoreFile, err)
+	}
+	dockerfile := b.Dockerfile
+	if dockerfile == "" {
+		dockerfile = defaultDockerfile
+	}
+	patterns = append(patterns, "!"+filepath.Clean(dockerfile), "!"+dockerignoreFile)
+	return patternmatcher.New(patterns)
+}
+
+// contextTar streams a tar archive of the build context. Errors creating the archive are returned by the reader.
+// The reader must be closed so that the archive stops being created if the reader isn't read to the end.
+func (b *BuildOptions) contextTar() (io.ReadCloser, error) {
+	fsys, err := b.contextFS()
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := b.excludes(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(w)
+		err := b.writeContextTar(tw, fsys, excludes)
+		if err == nil {
+			err = tw.Close()
+		}
+		w.CloseWithError(err) // nolint:errcheck
+	}()
+	return r, nil
+}
+
+// writeContextTar writes the files in the build context that aren't excluded to the tar archive
+func (b *BuildOptions) writeContextTar(tw *tar.Writer, fsys fs.FS, excludes *patternmatcher.PatternMatcher) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+		excluded, err := excludes.MatchesOrParentMatches(filepath.FromSlash(p))
+		if err != nil {
+			return err
+		}
+		if excluded {
+			// The files in an excluded directory can only be included again by an exclusion. e.g. !node_modules/a
+			if d.IsDir() && !excludes.Exclusions() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := b.readLink(fsys, p)
+			if err != nil {
+				return err
+			}
+			hdr := &tar.Header{Typeflag: tar.TypeSymlink, Name: p, Linkname: target, Mode: 0o777}
+			if info, err := d.Info(); err == nil {
+				hdr.ModTime = info.ModTime()
+			}
+			return tw.WriteHeader(hdr)
+		case d.IsDir() || d.Type().IsRegular():
+			return writeFileTar(tw, fsys, p, p)
+		default:
+			// Sockets, named pipes, and devices can't be sent to the Docker daemon
+			return nil
+		}
+	})
+}
+
+// genBuildImageName generates a unique image name for a built image.
+// The image name's repository is the given image name without any tag.
+func genBuildImageName(imgName string) string {
+	repository := imgName
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	if repository == "" {
+		repository = defaultBuildRepository
+	}
+	return repository + ":" + buildTagPrefix + strings.ToLower(randString(10))
+}
+
+// buildImage builds the image and returns the built image's name
+func buildImage(ctx context.Context, lgr Logger, dc ImageAPIClient, imgName, platform string,
+	opts *BuildOptions) (string, error) {
+	buildContext, err := opts.contextTar()
+	if err != nil {
+		return "", err
+	}
+	defer buildContext.Close() // nolint:errcheck
+
+	builtImgName := genBuildImageName(imgName)
+	lgr.Log("Building image:", builtImgName)
+
+	resp, err := dc.ImageBuild(ctx, buildContext, build.ImageBuildOptions{
+		Tags:        []string{builtImgName},
+		Remove:      true,
+		ForceRemove: true,
+		Dockerfile:  opts.Dockerfile,
+		BuildArgs:   opts.BuildArgs,
+		Labels:      resourceLabels(lgr),
+		Target:      opts.Target,
+		Platform:    platform,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			lgr.Log("Failed to close image build response:", err)
+		}
+	}()
+
+	output := strings.Builder{}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if msg.Error != nil {
+			lgr.Log("Image build output:", output.String())
+			return "", msg.Error
+		} else if msg.ErrorMessage != "" {
+			lgr.Log("Image build output:", output.String())
+			return "", errors.New(msg.ErrorMessage)
+		}
+		output.WriteString(msg.Stream)
+	}
+	lgr.Log("Image build output:", output.String())
+	lgr.Log("Built image:", builtImgName)
+
+	return builtImgName, nil
+}
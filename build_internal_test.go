@@ -0,0 +1,208 @@
+package dktest
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildOptionsContextTar(t *testing.T) {
+	contextDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte("FROM alpine"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	contextFS := fstest.MapFS{
+		"Dockerfile":  {Data: []byte("FROM alpine")},
+		"app/main.go": {Data: []byte("package main")},
+	}
+	ignoreFS := fstest.MapFS{
+		".dockerignore":       {Data: []byte("node_modules\n.git\n*.md\n!README.md\nDockerfile\n")},
+		"Dockerfile":          {Data: []byte("FROM alpine")},
+		"README.md":           {Data: []byte("readme")},
+		"CHANGELOG.md":        {Data: []byte("changelog")},
+		".git/HEAD":           {Data: []byte("ref")},
+		"node_modules/a/a.js": {Data: []byte("a")},
+		"app/main.go":         {Data: []byte("package main")},
+	}
+
+	testCases := []struct {
+		name        string
+		opts        BuildOptions
+		expected    map[string]string
+		expectedErr error
+	}{
+		{name: "no context", opts: BuildOptions{}, expectedErr: errBuildContext},
+		{name: "multiple contexts", opts: BuildOptions{ContextDir: contextDir, ContextFS: contextFS},
+			expectedErr: errBuildContext},
+		{name: "context dir", opts: BuildOptions{ContextDir: contextDir},
+			expected: map[string]string{"Dockerfile": "FROM alpine"}},
+		{name: "context fs", opts: BuildOptions{ContextFS: contextFS},
+			expected: map[string]string{"Dockerfile": "FROM alpine", "app/": "", "app/main.go": "package main"}},
+		{name: "dockerignore", opts: BuildOptions{ContextFS: ignoreFS},
+			expected: map[string]string{".dockerignore": "node_modules\n.git\n*.md\n!README.md\nDockerfile\n",
+				"Dockerfile": "FROM alpine", "README.md": "readme", "app/": "", "app/main.go": "package main"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := tc.opts.contextTar()
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatal("Got unexpected error:", err, "!=", tc.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.expected, readTar(t, r))
+		})
+	}
+}
+
+// readLinkFS is a file system that supports ReadLink
+type readLinkFS struct {
+	fs.FS
+	links map[string]string
+}
+
+func (f readLinkFS) ReadLink(name string) (string, error) {
+	if target, ok := f.links[name]; ok {
+		return target, nil
+	}
+	return "", fs.ErrNotExist
+}
+
+func TestBuildOptionsContextTarSymlinks(t *testing.T) {
+	contextDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(contextDir, "app"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(contextDir, "app", "main.go"), []byte("package main"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("app", filepath.Join(contextDir, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing", filepath.Join(contextDir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the fs.FS methods of the MapFS are exposed since newer versions of MapFS support ReadLink
+	linkFS := struct{ fs.FS }{fstest.MapFS{"linked": {Data: []byte("app"), Mode: fs.ModeSymlink}}}
+
+	readLinks := func(t *testing.T, opts *BuildOptions) map[string]string {
+		t.Helper()
+		r, err := opts.contextTar()
+		testErr(t, err, false)
+		defer r.Close() // nolint:errcheck
+		links := make(map[string]string)
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return links
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Typeflag == tar.TypeSymlink {
+				links[hdr.Name] = hdr.Linkname
+			}
+		}
+	}
+
+	t.Run("context dir", func(t *testing.T) {
+		assert.Equal(t, map[string]string{"linked": "app", "dangling": "missing"},
+			readLinks(t, &BuildOptions{ContextDir: contextDir}))
+	})
+
+	t.Run("context fs with ReadLink", func(t *testing.T) {
+		assert.Equal(t, map[string]string{"linked": "app"}, readLinks(t, &BuildOptions{ContextFS: readLinkFS{
+			FS: linkFS, links: map[string]string{"linked": "app"}}}))
+	})
+
+	t.Run("context fs without ReadLink", func(t *testing.T) {
+		r, err := (&BuildOptions{ContextFS: linkFS}).contextTar()
+		testErr(t, err, false)
+		defer r.Close() // nolint:errcheck
+		_, err = io.ReadAll(r)
+		if !errors.Is(err, errFileType) {
+			t.Error("Expected error:", errFileType, "but got:", err)
+		}
+	})
+}
+
+func TestGenBuildImageName(t *testing.T) {
+	testCases := []struct {
+		imgName            string
+		expectedRepository string
+	}{
+		{imgName: "", expectedRepository: "dktest"},
+		{imgName: "myservice", expectedRepository: "myservice"},
+		{imgName: "myservice:latest", expectedRepository: "myservice"},
+		{imgName: "localhost:5000/myservice", expectedRepository: "localhost:5000/myservice"},
+		{imgName: "localhost:5000/myservice:v1", expectedRepository: "localhost:5000/myservice"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.imgName, func(t *testing.T) {
+			re := regexp.MustCompile("^" + regexp.QuoteMeta(tc.expectedRepository) + ":dktest_[a-z0-9]{10}$")
+			if name := genBuildImageName(tc.imgName); !re.MatchString(name) {
+				t.Error("Built image name does not match expected:", name, "!~", re)
+			}
+		})
+	}
+}
+
+func TestBuildImage(t *testing.T) {
+	buildOpts := &BuildOptions{ContextFS: fstest.MapFS{"Dockerfile": {Data: []byte("FROM alpine")}}}
+	successResp := `{"stream":"Step 1/1 : FROM alpine\n"}
+{"stream":"Successfully built 0123456789ab\n"}`
+	errorResp := `{"stream":"Step 1/1 : FROM alpine\n"}
+{"errorDetail":{"message":"pull access denied"},"error":"pull access denied"}`
+
+	testCases := []struct {
+		name      string
+		client    mockdockerclient.ImageAPIClient
+		opts      *BuildOptions
+		expectErr bool
+	}{
+		{name: "success", client: mockdockerclient.ImageAPIClient{
+			BuildResp: io.NopCloser(strings.NewReader(successResp))}, opts: buildOpts, expectErr: false},
+		{name: "invalid build context", client: mockdockerclient.ImageAPIClient{
+			BuildResp: io.NopCloser(strings.NewReader(successResp))}, opts: &BuildOptions{}, expectErr: true},
+		{name: "build error", client: mockdockerclient.ImageAPIClient{}, opts: buildOpts, expectErr: true},
+		{name: "build output error", client: mockdockerclient.ImageAPIClient{
+			BuildResp: io.NopCloser(strings.NewReader(errorResp))}, opts: buildOpts, expectErr: true},
+		{name: "build output error message", client: mockdockerclient.ImageAPIClient{
+			BuildResp: io.NopCloser(strings.NewReader(`{"error":"failed to solve"}`))}, opts: buildOpts,
+			expectErr: true},
+		{name: "malformed build output", client: mockdockerclient.ImageAPIClient{
+			BuildResp: io.NopCloser(strings.NewReader("{"))}, opts: buildOpts, expectErr: true},
+		{name: "close error", client: mockdockerclient.ImageAPIClient{
+			BuildResp: mockdockerclient.MockReadCloser{
+				MockReader: mockdockerclient.MockReader{Err: io.EOF},
+				MockCloser: mockdockerclient.MockCloser{Err: mockdockerclient.Err},
+			}}, opts: buildOpts, expectErr: false},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			builtImgName, err := buildImage(ctx, t, &client, "myservice", "", tc.opts)
+			testErr(t, err, tc.expectErr)
+			if !tc.expectErr && !strings.HasPrefix(builtImgName, "myservice:dktest_") {
+				t.Error("Unexpected built image name:", builtImgName)
+			}
+		})
+	}
+}
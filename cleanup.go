@@ -0,0 +1,166 @@
+package dktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Filter selects the resources created by dktest using their labels. Unspecified fields match any resource, so the
+// zero Filter matches every resource created by dktest.
+type Filter struct {
+	// Session matches resources created by the session with the given ID. See SessionLabel
+	Session string
+	// Test matches resources created by the test with the given name. See TestLabel
+	Test string
+	// Hostname matches resources created by the host with the given hostname. See HostnameLabel
+	Hostname string
+	// PID matches resources created by the process with the given ID. See PIDLabel
+	PID int
+	// OlderThan matches resources created more than the given duration ago. See CreatedLabel
+	// Resources without a valid CreatedLabel don't match.
+	OlderThan time.Duration
+	// ExcludeKept excludes reusable containers and the resources created with KeepOnFailure since they're meant to
+	// outlive their session. See ReuseLabel and KeepOnFailureLabel
+	ExcludeKept bool
+}
+
+// Args gets the Docker filter args used to list the resources matched by the Filter.
+// OlderThan can't be expressed as a Docker filter, so Matches also needs to be used for each listed resource.
+func (f Filter) Args() filters.Args {
+	args := filters.NewArgs(filters.Arg("label", Label))
+	if f.Session != "" {
+		args.Add("label", SessionLabel+"="+f.Session)
+	}
+	if f.Test != "" {
+		args.Add("label", TestLabel+"="+f.Test)
+	}
+	if f.Hostname != "" {
+		args.Add("label", HostnameLabel+"="+f.Hostname)
+	}
+	if f.PID != 0 {
+		args.Add("label", PIDLabel+"="+strconv.Itoa(f.PID))
+	}
+	return args
+}
+
+// Matches checks if a resource with the given labels is matched by the Filter
+func (f Filter) Matches(labels map[string]string) bool {
+	if _, ok := labels[Label]; !ok {
+		return false
+	}
+	for l, v := range map[string]string{SessionLabel: f.Session, TestLabel: f.Test, HostnameLabel: f.Hostname} {
+		if v != "" && labels[l] != v {
+			return false
+		}
+	}
+	if f.PID != 0 && labels[PIDLabel] != strconv.Itoa(f.PID) {
+		return false
+	}
+	if f.ExcludeKept {
+		if _, ok := labels[ReuseLabel]; ok {
+			return false
+		}
+		if _, ok := labels[KeepOnFailureLabel]; ok {
+			return false
+		}
+	}
+	if f.OlderThan > 0 {
+		created, err := time.Parse(time.RFC3339, labels[CreatedLabel])
+		if err != nil || time.Since(created) < f.OlderThan {
+			return false
+		}
+	}
+	return true
+}
+
+// Cleanup removes the containers, networks, and volumes created by dktest that are matched by the filter.
+// The Docker client is created using the environment's Docker configuration. e.g. DOCKER_HOST
+// Cleanup is a safer alternative to pruning all resources with the dktest label since it only removes the resources
+// created by a specific session, test, host, or process.
+func Cleanup(ctx context.Context, filter Filter) error {
+	dc, closeClient, err := getClient(ctx, nopLogger{}, Options{})
+	if err != nil {
+		return fmt.Errorf("error getting Docker client: %w", err)
+	}
+	defer closeClient() // nolint:errcheck
+	return CleanupWithClient(ctx, nopLogger{}, dc, filter)
+}
+
+// CleanupWithClient is the same as Cleanup but uses the given Docker client and logs each removed resource
+func CleanupWithClient(ctx context.Context, lgr Logger, dc Client, filter Filter) error {
+	return removeResources(ctx, lgr, dc, filter, nil)
+}
+
+// removeResources removes the containers, networks, and volumes matched by the filter, except for the resources whose
+// ID or name is kept
+func removeResources(ctx context.Context, lgr Logger, dc Client, filter Filter,
+	keep map[string]struct{}) error {
+	kept := func(ids ...string) bool {
+		for _, id := range ids {
+			if _, ok := keep[strings.TrimPrefix(id, "/")]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	args := filter.Args()
+	var errs []error
+
+	// Containers are removed first since networks and volumes can't be removed while they're used by a container
+	containers, err := dc.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing containers: %w", err))
+	}
+	for _, c := range containers {
+		if !filter.Matches(c.Labels) || kept(append([]string{c.ID}, c.Names...)...) {
+			continue
+		}
+		if err := dc.ContainerRemove(ctx, c.ID, container.RemoveOptions{RemoveVolumes: true,
+			Force: true}); err != nil {
+			errs = append(errs, fmt.Errorf("error removing container: %v error: %w", c.ID, err))
+			continue
+		}
+		lgr.Log("Removed container:", c.ID)
+	}
+
+	networks, err := dc.NetworkList(ctx, network.ListOptions{Filters: args})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing networks: %w", err))
+	}
+	for _, n := range networks {
+		if !filter.Matches(n.Labels) || kept(n.ID, n.Name) {
+			continue
+		}
+		if err := dc.NetworkRemove(ctx, n.ID); err != nil {
+			errs = append(errs, fmt.Errorf("error removing network: %v error: %w", n.Name, err))
+			continue
+		}
+		lgr.Log("Removed network:", n.Name)
+	}
+
+	volumes, err := dc.VolumeList(ctx, volume.ListOptions{Filters: args})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing volumes: %w", err))
+	}
+	for _, v := range volumes.Volumes {
+		if v == nil || !filter.Matches(v.Labels) || kept(v.Name) {
+			continue
+		}
+		if err := dc.VolumeRemove(ctx, v.Name, true); err != nil {
+			errs = append(errs, fmt.Errorf("error removing volume: %v error: %w", v.Name, err))
+			continue
+		}
+		lgr.Log("Removed volume:", v.Name)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,100 @@
+package dktest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   Filter
+		expected filters.Args
+	}{
+		{name: "zero", filter: Filter{}, expected: filters.NewArgs(filters.Arg("label", Label))},
+		{name: "all", filter: Filter{Session: "session", Test: "TestFoo", Hostname: "host", PID: 123,
+			OlderThan: time.Hour}, expected: filters.NewArgs(
+			filters.Arg("label", Label),
+			filters.Arg("label", SessionLabel+"=session"),
+			filters.Arg("label", TestLabel+"=TestFoo"),
+			filters.Arg("label", HostnameLabel+"=host"),
+			filters.Arg("label", PIDLabel+"=123"),
+		)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.filter.Args())
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	created := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	labels := map[string]string{Label: "true", SessionLabel: "session", TestLabel: "TestFoo", HostnameLabel: "host",
+		PIDLabel: "123", CreatedLabel: created}
+
+	testCases := []struct {
+		name     string
+		filter   Filter
+		labels   map[string]string
+		expected bool
+	}{
+		{name: "zero", filter: Filter{}, labels: labels, expected: true},
+		{name: "not dktest", filter: Filter{}, labels: map[string]string{}, expected: false},
+		{name: "session", filter: Filter{Session: "session"}, labels: labels, expected: true},
+		{name: "other session", filter: Filter{Session: "other"}, labels: labels, expected: false},
+		{name: "test", filter: Filter{Test: "TestFoo"}, labels: labels, expected: true},
+		{name: "other test", filter: Filter{Test: "TestBar"}, labels: labels, expected: false},
+		{name: "hostname", filter: Filter{Hostname: "host"}, labels: labels, expected: true},
+		{name: "other hostname", filter: Filter{Hostname: "other"}, labels: labels, expected: false},
+		{name: "pid", filter: Filter{PID: 123}, labels: labels, expected: true},
+		{name: "other pid", filter: Filter{PID: 456}, labels: labels, expected: false},
+		{name: "older than", filter: Filter{OlderThan: time.Hour}, labels: labels, expected: true},
+		{name: "newer than", filter: Filter{OlderThan: 3 * time.Hour}, labels: labels, expected: false},
+		{name: "older than - no created label", filter: Filter{OlderThan: time.Hour},
+			labels: map[string]string{Label: "true"}, expected: false},
+		{name: "exclude kept", filter: Filter{ExcludeKept: true}, labels: labels, expected: true},
+		{name: "exclude kept - reusable", filter: Filter{ExcludeKept: true},
+			labels: map[string]string{Label: "true", ReuseLabel: "hash"}, expected: false},
+		{name: "exclude kept - keep on failure", filter: Filter{ExcludeKept: true},
+			labels: map[string]string{Label: "true", KeepOnFailureLabel: "true"}, expected: false},
+		{name: "reusable", filter: Filter{}, labels: map[string]string{Label: "true", ReuseLabel: "hash"},
+			expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.filter.Matches(tc.labels))
+		})
+	}
+}
+
+func TestRemoveResources(t *testing.T) {
+	oldLabels := map[string]string{Label: "true", TestLabel: "TestFoo",
+		CreatedLabel: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)}
+	newLabels := map[string]string{Label: "true", TestLabel: "TestFoo",
+		CreatedLabel: time.Now().UTC().Format(time.RFC3339)}
+	client := &mockdockerclient.Client{
+		ContainerAPIClient: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{
+			{ID: "oldID", Labels: oldLabels}, {ID: "newID", Labels: newLabels}}},
+		NetworkAPIClient: mockdockerclient.NetworkAPIClient{ListResp: []network.Summary{
+			{Name: "old", Labels: oldLabels}, {Name: "new", Labels: newLabels}}},
+		VolumeAPIClient: mockdockerclient.VolumeAPIClient{ListResp: &volume.ListResponse{Volumes: []*volume.Volume{
+			{Name: "old", Labels: oldLabels}, {Name: "new", Labels: newLabels}}}},
+	}
+
+	lgr := &recordingLogger{}
+	err := removeResources(context.Background(), lgr, client, Filter{Test: "TestFoo", OlderThan: time.Hour}, nil)
+	testErr(t, err, false)
+	assert.Equal(t, [][]interface{}{{"Removed container:", "oldID"}, {"Removed network:", "old"},
+		{"Removed volume:", "old"}}, lgr.logs)
+}
@@ -0,0 +1,132 @@
+package dktest
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// apiVersionEnvVar is the environment variable used to pin the Docker API version used by dktest
+	apiVersionEnvVar = "DKTEST_DOCKER_API_VERSION"
+)
+
+// ContainerAPIClient is the subset of Docker's client.ContainerAPIClient used by dktest
+type ContainerAPIClient interface {
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig,
+		networkingConfig *network.NetworkingConfig, platform *v1.Platform,
+		containerName string) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, container string, options container.StartOptions) error
+	ContainerInspect(ctx context.Context, container string) (container.InspectResponse, error)
+	ContainerLogs(ctx context.Context, container string, options container.LogsOptions) (io.ReadCloser, error)
+	ContainerStop(ctx context.Context, container string, options container.StopOptions) error
+	ContainerRemove(ctx context.Context, container string, options container.RemoveOptions) error
+	ContainerWait(ctx context.Context, container string,
+		condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error)
+	ContainerExecCreate(ctx context.Context, container string,
+		options container.ExecOptions) (container.ExecCreateResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string,
+		options container.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+	CopyToContainer(ctx context.Context, container, path string, content io.Reader,
+		options container.CopyToContainerOptions) error
+	CopyFromContainer(ctx context.Context, container, srcPath string) (io.ReadCloser, container.PathStat, error)
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+}
+
+// ImageAPIClient is the subset of Docker's client.ImageAPIClient used by dktest
+type ImageAPIClient interface {
+	ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error)
+	ImageRemove(ctx context.Context, image string, options image.RemoveOptions) ([]image.DeleteResponse, error)
+	ImageBuild(ctx context.Context, context io.Reader, options build.ImageBuildOptions) (build.ImageBuildResponse,
+		error)
+	ImageInspect(ctx context.Context, image string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImageLoad(ctx context.Context, input io.Reader, opts ...client.ImageLoadOption) (image.LoadResponse, error)
+}
+
+// NetworkAPIClient is the subset of Docker's client.NetworkAPIClient used by dktest
+type NetworkAPIClient interface {
+	NetworkCreate(ctx context.Context, name string, options network.CreateOptions) (network.CreateResponse, error)
+	NetworkRemove(ctx context.Context, network string) error
+	NetworkList(ctx context.Context, options network.ListOptions) ([]network.Summary, error)
+}
+
+// VolumeAPIClient is the subset of Docker's client.VolumeAPIClient used by dktest
+type VolumeAPIClient interface {
+	VolumeList(ctx context.Context, options volume.ListOptions) (volume.ListResponse, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+}
+
+// Client is the subset of the Docker client API used by dktest.
+// The official Docker client satisfies this interface, as do the mocks in the mockdockerclient package.
+type Client interface {
+	ContainerAPIClient
+	ImageAPIClient
+	NetworkAPIClient
+	VolumeAPIClient
+}
+
+var _ Client = (*client.Client)(nil)
+
+// apiVersion gets the Docker API version to pin the client to. An empty version means that the API version should be
+// negotiated with the Docker daemon.
+func apiVersion(opts Options) string {
+	if opts.DockerAPIVersion != "" {
+		return opts.DockerAPIVersion
+	}
+	return os.Getenv(apiVersionEnvVar)
+}
+
+// getClient gets the Docker client specified by the Options. If no client is specified, a new client is created
+// using the environment's Docker configuration. The returned close func only closes the client if it was created by
+// dktest since the caller owns any client they specify.
+func getClient(ctx context.Context, lgr Logger, opts Options) (Client, func() error, error) {
+	if opts.Client != nil {
+		return opts.Client, func() error { return nil }, nil
+	}
+
+	clientOpts := []client.Opt{client.FromEnv}
+	version := apiVersion(opts)
+	if version != "" {
+		clientOpts = append(clientOpts, client.WithVersion(version))
+	} else {
+		clientOpts = append(clientOpts, client.WithAPIVersionNegotiation())
+	}
+	dc, err := client.NewClientWithOpts(clientOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if version != "" {
+		lgr.Log("Using Docker API version:", dc.ClientVersion())
+	} else if ping, err := pingDaemon(ctx, dc, opts); err != nil {
+		// The client's default API version is used until negotiation succeeds
+		lgr.Log("Failed to negotiate Docker API version, using default:", dc.ClientVersion(), "error:", err)
+	} else {
+		dc.NegotiateAPIVersionPing(ping)
+		lgr.Log("Negotiated Docker API version:", dc.ClientVersion())
+	}
+	return dc, dc.Close, nil
+}
+
+// pingDaemon pings the Docker daemon within the PullTimeout since the ping is the first call to the Docker daemon.
+// The Options may not be initialized yet, so the DefaultPullTimeout is used if no PullTimeout is specified.
+func pingDaemon(ctx context.Context, dc *client.Client, opts Options) (types.Ping, error) {
+	timeout := opts.PullTimeout
+	if timeout <= 0 {
+		timeout = DefaultPullTimeout
+	}
+	ctx, cancelFunc := context.WithTimeout(ctx, timeout)
+	defer cancelFunc()
+	return dc.Ping(ctx)
+}
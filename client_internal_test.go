@@ -0,0 +1,135 @@
+package dktest
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/client"
+)
+
+var _ Client = (*mockdockerclient.Client)(nil)
+
+func TestAPIVersion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     Options
+		env      string
+		expected string
+	}{
+		{name: "negotiated", opts: Options{}, expected: ""},
+		{name: "env", opts: Options{}, env: "1.44", expected: "1.44"},
+		{name: "options", opts: Options{DockerAPIVersion: "1.45"}, expected: "1.45"},
+		{name: "options preferred over env", opts: Options{DockerAPIVersion: "1.45"}, env: "1.44",
+			expected: "1.45"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(apiVersionEnvVar, tc.env)
+			if v := apiVersion(tc.opts); v != tc.expected {
+				t.Error("API version does not match expected:", v, "!=", tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("specified client", func(t *testing.T) {
+		mockClient := &mockdockerclient.Client{}
+		dc, closeClient, err := getClient(ctx, t, Options{Client: mockClient})
+		if err != nil {
+			t.Fatal("Got unexpected error:", err)
+		}
+		if dc != mockClient {
+			t.Error("Expected the specified client to be used")
+		}
+		if err := closeClient(); err != nil {
+			t.Error("Got unexpected error closing client:", err)
+		}
+	})
+
+	t.Run("new client - pinned version", func(t *testing.T) {
+		dc, closeClient, err := getClient(ctx, t, Options{DockerAPIVersion: "1.44"})
+		if err != nil {
+			t.Fatal("Got unexpected error:", err)
+		}
+		if v := dc.(*client.Client).ClientVersion(); v != "1.44" {
+			t.Error("Client version does not match expected:", v, "!= 1.44")
+		}
+		if err := closeClient(); err != nil {
+			t.Error("Got unexpected error closing client:", err)
+		}
+	})
+
+	t.Run("new client - negotiated version", func(t *testing.T) {
+		t.Setenv(apiVersionEnvVar, "")
+		dc, closeClient, err := getClient(ctx, t, Options{})
+		if err != nil {
+			t.Fatal("Got unexpected error:", err)
+		}
+		if dc == nil {
+			t.Error("Expected a new client")
+		}
+		if err := closeClient(); err != nil {
+			t.Error("Got unexpected error closing client:", err)
+		}
+	})
+
+	t.Run("new client - negotiation failed", func(t *testing.T) {
+		t.Setenv(apiVersionEnvVar, "")
+		t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(t.TempDir(), "missing.sock"))
+		lgr := &recordingLogger{}
+		_, closeClient, err := getClient(ctx, lgr, Options{})
+		if err != nil {
+			t.Fatal("Got unexpected error:", err)
+		}
+		if !lgr.logged("Failed to negotiate Docker API version, using default:") {
+			t.Error("Expected the failed negotiation to be logged")
+		}
+		if lgr.logged("Negotiated Docker API version:") {
+			t.Error("Expected the negotiated version to not be logged")
+		}
+		if err := closeClient(); err != nil {
+			t.Error("Got unexpected error closing client:", err)
+		}
+	})
+
+	t.Run("new client - unresponsive daemon", func(t *testing.T) {
+		// The daemon accepts connections but never responds
+		sock := filepath.Join(t.TempDir(), "docker.sock")
+		l, err := net.Listen("unix", sock)
+		if err != nil {
+			t.Fatal("Error listening:", err)
+		}
+		defer l.Close() // nolint:errcheck
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close() // nolint:errcheck
+			}
+		}()
+		t.Setenv(apiVersionEnvVar, "")
+		t.Setenv("DOCKER_HOST", "unix://"+sock)
+
+		lgr := &recordingLogger{}
+		_, closeClient, err := getClient(ctx, lgr, Options{PullTimeout: 50 * time.Millisecond})
+		if err != nil {
+			t.Fatal("Got unexpected error:", err)
+		}
+		if !lgr.logged("Failed to negotiate Docker API version, using default:") {
+			t.Error("Expected the failed negotiation to be logged")
+		}
+		if err := closeClient(); err != nil {
+			t.Error("Got unexpected error closing client:", err)
+		}
+	})
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/dhui/dktest"
+)
+
+// shortIDLen is the length of the shortened IDs printed by ls. Same as the Docker CLI.
+const shortIDLen = 12
+
+// resource is a container, network, or volume created by dktest
+type resource struct {
+	Type   string
+	ID     string
+	Name   string
+	State  string
+	Labels map[string]string
+}
+
+// writerLogger logs messages to a writer, one message per line
+type writerLogger struct{ w io.Writer }
+
+func (l writerLogger) Log(args ...interface{}) { fmt.Fprintln(l.w, args...) } // nolint:errcheck
+
+// newFlagSet creates a FlagSet for the command with flags for the filter. The test flag is only added if withTest is
+// true since some commands take the test name as an argument.
+func newFlagSet(name string, filter *dktest.Filter, withTest bool, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&filter.Session, "session", "", "only match resources created by the session with the given ID")
+	if withTest {
+		fs.StringVar(&filter.Test, "test", "", "only match resources created by the test with the given name")
+	}
+	fs.StringVar(&filter.Hostname, "hostname", "", "only match resources created by the host with the given hostname")
+	fs.IntVar(&filter.PID, "pid", 0, "only match resources created by the process with the given ID")
+	fs.DurationVar(&filter.OlderThan, "older-than", 0,
+		"only match resources created more than the given duration ago. e.g. 1h")
+	return fs
+}
+
+// parseFlags parses the args and wraps errors other than flag.ErrHelp as usage errors
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+// listContainers lists the containers matched by the filter
+func listContainers(ctx context.Context, dc dktest.Client, filter dktest.Filter) ([]container.Summary, error) {
+	containers, err := dc.ContainerList(ctx, container.ListOptions{All: true, Filters: filter.Args()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing containers: %w", err)
+	}
+	matched := make([]container.Summary, 0, len(containers))
+	for _, c := range containers {
+		if filter.Matches(c.Labels) {
+			matched = append(matched, c)
+		}
+	}
+	return matched, nil
+}
+
+// listResources lists the containers, networks, and volumes matched by the filter
+func listResources(ctx context.Context, dc dktest.Client, filter dktest.Filter) ([]resource, error) {
+	var resources []resource
+
+	containers, err := listContainers(ctx, dc, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		resources = append(resources, resource{Type: "container", ID: c.ID, Name: containerName(c), State: c.State,
+			Labels: c.Labels})
+	}
+
+	networks, err := dc.NetworkList(ctx, network.ListOptions{Filters: filter.Args()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing networks: %w", err)
+	}
+	for _, n := range networks {
+		if filter.Matches(n.Labels) {
+			resources = append(resources, resource{Type: "network", ID: n.ID, Name: n.Name, Labels: n.Labels})
+		}
+	}
+
+	volumes, err := dc.VolumeList(ctx, volume.ListOptions{Filters: filter.Args()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing volumes: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		if v != nil && filter.Matches(v.Labels) {
+			resources = append(resources, resource{Type: "volume", Name: v.Name, Labels: v.Labels})
+		}
+	}
+
+	return resources, nil
+}
+
+func containerName(c container.Summary) string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+func shortID(id string) string {
+	if len(id) > shortIDLen {
+		return id[:shortIDLen]
+	}
+	return id
+}
+
+// orDash replaces empty values with a dash so that the columns printed by ls line up
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func printResources(w io.Writer, resources []resource) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tID\tNAME\tSTATE\tTEST\tSESSION\tHOSTNAME\tPID\tCREATED") // nolint:errcheck
+	for _, r := range resources {
+		columns := []string{r.Type, shortID(r.ID), r.Name, r.State, r.Labels[dktest.TestLabel],
+			r.Labels[dktest.SessionLabel], r.Labels[dktest.HostnameLabel], r.Labels[dktest.PIDLabel],
+			r.Labels[dktest.CreatedLabel]}
+		for i, c := range columns {
+			columns[i] = orDash(c)
+		}
+		fmt.Fprintln(tw, strings.Join(columns, "\t")) // nolint:errcheck
+	}
+	return tw.Flush()
+}
+
+// ls lists the resources created by dktest
+func ls(ctx context.Context, dc dktest.Client, args []string, stdout, stderr io.Writer) error {
+	var filter dktest.Filter
+	fs := newFlagSet("ls", &filter, true, stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: ls takes no arguments", errUsage)
+	}
+
+	resources, err := listResources(ctx, dc, filter)
+	if err != nil {
+		return err
+	}
+	return printResources(stdout, resources)
+}
+
+// prune removes the resources created by dktest. Reusable containers and the resources created with KeepOnFailure
+// are only removed if -all is specified since they're meant to outlive their session.
+func prune(ctx context.Context, dc dktest.Client, args []string, stdout, stderr io.Writer) error {
+	var filter dktest.Filter
+	fs := newFlagSet("prune", &filter, true, stderr)
+	dryRun := fs.Bool("dry-run", false, "list the resources that would be removed without removing them")
+	all := fs.Bool("all", false, "also remove reusable containers and the resources created with KeepOnFailure")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: prune takes no arguments", errUsage)
+	}
+	filter.ExcludeKept = !*all
+
+	if *dryRun {
+		resources, err := listResources(ctx, dc, filter)
+		if err != nil {
+			return err
+		}
+		return printResources(stdout, resources)
+	}
+	return dktest.CleanupWithClient(ctx, writerLogger{w: stdout}, dc, filter)
+}
+
+// logs prints the logs of the containers created by a test
+func logs(ctx context.Context, dc dktest.Client, args []string, stdout, stderr io.Writer) error {
+	var filter dktest.Filter
+	fs := newFlagSet("logs", &filter, false, stderr)
+	tail := fs.String("tail", "all", "number of lines to show from the end of the logs of each container")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: logs takes exactly one test name", errUsage)
+	}
+	filter.Test = fs.Arg(0)
+
+	containers, err := listContainers(ctx, dc, filter)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		return fmt.Errorf("no containers found for test: %v", filter.Test)
+	}
+
+	for _, c := range containers {
+		if _, err := fmt.Fprintf(stdout, "==> %s (%s) <==\n", containerName(c), shortID(c.ID)); err != nil {
+			return err
+		}
+		r, err := dc.ContainerLogs(ctx, c.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true,
+			Tail: *tail})
+		if err != nil {
+			return fmt.Errorf("error getting container logs: %v error: %w", c.ID, err)
+		}
+		_, err = stdcopy.StdCopy(stdout, stderr, r)
+		r.Close() // nolint:errcheck
+		if err != nil {
+			return fmt.Errorf("error reading container logs: %v error: %w", c.ID, err)
+		}
+	}
+	return nil
+}
+
+// inspect prints the details of the containers created by dktest as JSON. If no containers are specified, the
+// containers matched by the filter are inspected.
+func inspect(ctx context.Context, dc dktest.Client, args []string, stdout, stderr io.Writer) error {
+	var filter dktest.Filter
+	fs := newFlagSet("inspect", &filter, true, stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	ids := fs.Args()
+	if len(ids) == 0 {
+		containers, err := listContainers(ctx, dc, filter)
+		if err != nil {
+			return err
+		}
+		for _, c := range containers {
+			ids = append(ids, c.ID)
+		}
+	}
+
+	resps := make([]container.InspectResponse, 0, len(ids))
+	for _, id := range ids {
+		resp, err := dc.ContainerInspect(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error inspecting container: %v error: %w", id, err)
+		}
+		var labels map[string]string
+		if resp.Config != nil {
+			labels = resp.Config.Labels
+		}
+		if !filter.Matches(labels) {
+			return fmt.Errorf("container doesn't match the filter or wasn't created by dktest: %v", id)
+		}
+		resps = append(resps, resp)
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "    ")
+	return enc.Encode(resps)
+}
+
+// reaper waits for stdin to be closed by the session's process and then removes the session's resources that weren't
+// kept by the session
+func reaper(ctx context.Context, dc dktest.Client, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("reaper", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	session := fs.String("session", "", "the ID of the session whose resources are removed")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *session == "" {
+		return fmt.Errorf("%w: reaper takes a session and no arguments", errUsage)
+	}
+	return dktest.Reap(ctx, writerLogger{w: stdout}, dc, *session, stdin)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dhui/dktest"
+	"github.com/dhui/dktest/mockdockerclient"
+)
+
+var (
+	fooLabels = map[string]string{dktest.Label: "true", dktest.SessionLabel: "session", dktest.TestLabel: "TestFoo",
+		dktest.HostnameLabel: "host", dktest.PIDLabel: "123", dktest.CreatedLabel: "2024-01-02T03:04:05Z"}
+	barLabels = map[string]string{dktest.Label: "true", dktest.TestLabel: "TestBar"}
+)
+
+func newClient(logs io.ReadCloser) *mockdockerclient.Client {
+	return &mockdockerclient.Client{
+		ContainerAPIClient: mockdockerclient.ContainerAPIClient{
+			ListResp: []container.Summary{
+				{ID: "0123456789abcdef", Names: []string{"/dktest_foo"}, State: container.StateRunning,
+					Labels: fooLabels},
+				{ID: "fedcba9876543210", Names: []string{"/dktest_bar"}, State: container.StateExited,
+					Labels: barLabels},
+			},
+			InspectResp: &container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{ID: "0123456789abcdef", Name: "/dktest_foo"},
+				Config:            &container.Config{Labels: fooLabels},
+			},
+			Logs: logs,
+		},
+		NetworkAPIClient: mockdockerclient.NetworkAPIClient{ListResp: []network.Summary{
+			{ID: "networkID", Name: "dktest_network", Labels: fooLabels}}},
+		VolumeAPIClient: mockdockerclient.VolumeAPIClient{ListResp: &volume.ListResponse{Volumes: []*volume.Volume{
+			{Name: "dktest_volume", Labels: barLabels}}}},
+	}
+}
+
+// newPruneClient creates a client that also lists a reusable container and a container and network kept on failure
+func newPruneClient() *mockdockerclient.Client {
+	client := newClient(nil)
+	client.ContainerAPIClient.ListResp = append(client.ContainerAPIClient.ListResp,
+		container.Summary{ID: "reusableID", Names: []string{"/dktest_reuse_hash"}, State: container.StateRunning,
+			Labels: map[string]string{dktest.Label: "true", dktest.ReuseLabel: "hash"}},
+		container.Summary{ID: "keptID", Names: []string{"/dktest_kept"}, State: container.StateRunning,
+			Labels: map[string]string{dktest.Label: "true", dktest.KeepOnFailureLabel: "true"}})
+	client.NetworkAPIClient.ListResp = append(client.NetworkAPIClient.ListResp, network.Summary{ID: "keptNetworkID",
+		Name: "dktest_kept_network", Labels: map[string]string{dktest.Label: "true", dktest.KeepOnFailureLabel: "true"}})
+	return client
+}
+
+func TestCommands(t *testing.T) {
+	var logs bytes.Buffer
+	if _, err := stdcopy.NewStdWriter(&logs, stdcopy.Stdout).Write([]byte("out\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdcopy.NewStdWriter(&logs, stdcopy.Stderr).Write([]byte("err\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name           string
+		client         *mockdockerclient.Client
+		args           []string
+		stdin          string
+		expectedStdout string
+		// stdoutContains is checked instead of expectedStdout if specified
+		stdoutContains string
+		expectedStderr string
+		expectedErr    error
+		expectErr      bool
+	}{
+		{name: "no command", client: newClient(nil), args: []string{}, expectedErr: errUsage, expectErr: true},
+		{name: "unknown command", client: newClient(nil), args: []string{"rm"}, expectedErr: errUsage,
+			expectErr: true},
+		{name: "ls", client: newClient(nil), args: []string{"ls"}, expectedStdout: "" +
+			"TYPE        ID             NAME             STATE     TEST      SESSION   HOSTNAME   PID   CREATED\n" +
+			"container   0123456789ab   dktest_foo       running   TestFoo   session   host       123   2024-01-02T03:04:05Z\n" +
+			"container   fedcba987654   dktest_bar       exited    TestBar   -         -          -     -\n" +
+			"network     networkID      dktest_network   -         TestFoo   session   host       123   2024-01-02T03:04:05Z\n" +
+			"volume      -              dktest_volume    -         TestBar   -         -          -     -\n",
+			expectErr: false},
+		{name: "ls - filtered", client: newClient(nil), args: []string{"ls", "-test", "TestFoo", "-older-than", "1h"},
+			expectedStdout: "" +
+				"TYPE        ID             NAME             STATE     TEST      SESSION   HOSTNAME   PID   CREATED\n" +
+				"container   0123456789ab   dktest_foo       running   TestFoo   session   host       123   2024-01-02T03:04:05Z\n" +
+				"network     networkID      dktest_network   -         TestFoo   session   host       123   2024-01-02T03:04:05Z\n",
+			expectErr: false},
+		{name: "ls - invalid flag", client: newClient(nil), args: []string{"ls", "-older-than", "soon"},
+			expectedErr: errUsage, expectErr: true},
+		{name: "ls - args", client: newClient(nil), args: []string{"ls", "TestFoo"}, expectedErr: errUsage,
+			expectErr: true},
+		{name: "ls - help", client: newClient(nil), args: []string{"ls", "-h"}, expectedErr: flag.ErrHelp,
+			expectErr: true},
+		{name: "ls - list error", client: &mockdockerclient.Client{ContainerAPIClient: mockdockerclient.ContainerAPIClient{
+			ListErr: mockdockerclient.Err}}, args: []string{"ls"},
+			expectedErr: mockdockerclient.Err, expectErr: true},
+		{name: "prune", client: newClient(nil), args: []string{"prune", "--older-than", "1h"}, expectedStdout: "" +
+			"Removed container: 0123456789abcdef\n" +
+			"Removed network: dktest_network\n", expectErr: false},
+		{name: "prune - kept excluded", client: newPruneClient(), args: []string{"prune"}, expectedStdout: "" +
+			"Removed container: 0123456789abcdef\n" +
+			"Removed container: fedcba9876543210\n" +
+			"Removed network: dktest_network\n" +
+			"Removed volume: dktest_volume\n", expectErr: false},
+		{name: "prune - all", client: newPruneClient(), args: []string{"prune", "-all"}, expectedStdout: "" +
+			"Removed container: 0123456789abcdef\n" +
+			"Removed container: fedcba9876543210\n" +
+			"Removed container: reusableID\n" +
+			"Removed container: keptID\n" +
+			"Removed network: dktest_network\n" +
+			"Removed network: dktest_kept_network\n" +
+			"Removed volume: dktest_volume\n", expectErr: false},
+		{name: "prune - dry run", client: newClient(nil), args: []string{"prune", "-dry-run", "-test", "TestBar"},
+			expectedStdout: "" +
+				"TYPE        ID             NAME            STATE    TEST      SESSION   HOSTNAME   PID   CREATED\n" +
+				"container   fedcba987654   dktest_bar      exited   TestBar   -         -          -     -\n" +
+				"volume      -              dktest_volume   -        TestBar   -         -          -     -\n",
+			expectErr: false},
+		{name: "logs", client: newClient(io.NopCloser(&logs)), args: []string{"logs", "TestFoo"},
+			expectedStdout: "==> dktest_foo (0123456789ab) <==\nout\n", expectedStderr: "err\n", expectErr: false},
+		{name: "logs - no containers", client: newClient(nil), args: []string{"logs", "TestBaz"}, expectErr: true},
+		{name: "logs - no test name", client: newClient(nil), args: []string{"logs"}, expectedErr: errUsage,
+			expectErr: true},
+		{name: "logs - logs error", client: newClient(nil), args: []string{"logs", "TestFoo"},
+			expectedStdout: "==> dktest_foo (0123456789ab) <==\n", expectedErr: mockdockerclient.Err,
+			expectErr: true},
+		{name: "inspect", client: newClient(nil), args: []string{"inspect", "dktest_foo"},
+			stdoutContains: `"Name": "/dktest_foo"`, expectErr: false},
+		{name: "inspect - filter mismatch", client: newClient(nil),
+			args: []string{"inspect", "-test", "TestBar", "dktest_foo"}, expectErr: true},
+		{name: "reaper", client: newClient(nil), args: []string{"reaper", "-session", "session"},
+			stdin: "keep dktest_network\n", expectedStdout: "Removed container: 0123456789abcdef\n", expectErr: false},
+		{name: "reaper - no session", client: newClient(nil), args: []string{"reaper"}, expectedErr: errUsage,
+			expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := run(context.Background(), tc.client, tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			testErr(t, err, tc.expectErr)
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error: %v but got: %v", tc.expectedErr, err)
+			}
+			if tc.stdoutContains != "" {
+				assert.Contains(t, stdout.String(), tc.stdoutContains)
+			} else {
+				assert.Equal(t, tc.expectedStdout, stdout.String())
+			}
+			if tc.expectedStderr != "" {
+				assert.Equal(t, tc.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func testErr(t *testing.T, err error, expectErr bool) {
+	t.Helper()
+	if err == nil && expectErr {
+		t.Error("Expected an error but didn't get one")
+	} else if err != nil && !expectErr {
+		t.Error("Got unexpected error:", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Command dktest lists, inspects, and removes the Docker resources created by dktest.
+//
+// The resources are found using the labels added by dktest, so only resources created by dktest are affected.
+// The Docker daemon is configured using the environment. e.g. DOCKER_HOST
+//
+// Usage:
+//
+//	dktest ls [filter flags]
+//	dktest prune [-dry-run] [-all] [filter flags]
+//	dktest logs [filter flags] <test-name>
+//	dktest inspect [filter flags] [container ...]
+//	dktest reaper -session <session-id>
+//
+// reaper is started by dktest when the DKTEST_REAPER environment variable is set. It removes the resources created by
+// the session once its stdin is closed, which happens when the test process exits.
+//
+// prune doesn't remove reusable containers or the resources created with KeepOnFailure unless -all is specified.
+//
+// The filter flags are:
+//
+//	-session string      only match resources created by the session with the given ID
+//	-test string         only match resources created by the test with the given name
+//	-hostname string     only match resources created by the host with the given hostname
+//	-pid int             only match resources created by the process with the given ID
+//	-older-than duration only match resources created more than the given duration ago. e.g. 1h
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/docker/docker/client"
+
+	"github.com/dhui/dktest"
+)
+
+const usage = `Usage: dktest <command> [flags] [args]
+
+Commands:
+  ls        list the containers, networks, and volumes created by dktest
+  prune     remove the containers, networks, and volumes created by dktest
+  logs      print the logs of the containers created by a test
+  inspect   print the details of the containers created by dktest as JSON
+  reaper    remove the resources created by a session once stdin is closed, started by dktest
+
+Run 'dktest <command> -h' for the flags of a command.
+`
+
+// errUsage is returned when the command line is invalid
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(runMain(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// runMain runs the command and returns the exit code
+func runMain(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage) // nolint:errcheck
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	dc, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		fmt.Fprintln(stderr, "error creating Docker client:", err) // nolint:errcheck
+		return 1
+	}
+	defer dc.Close() // nolint:errcheck
+
+	if err := run(ctx, dc, args, stdin, stdout, stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(stderr, "dktest:", err) // nolint:errcheck
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// run runs the command specified by the args using the Docker client
+func run(ctx context.Context, dc dktest.Client, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: no command", errUsage)
+	}
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "ls":
+		return ls(ctx, dc, args, stdout, stderr)
+	case "prune":
+		return prune(ctx, dc, args, stdout, stderr)
+	case "logs":
+		return logs(ctx, dc, args, stdout, stderr)
+	case "inspect":
+		return inspect(ctx, dc, args, stdout, stderr)
+	case "reaper":
+		return reaper(ctx, dc, args, stdin, stdout, stderr)
+	default:
+		return fmt.Errorf("%w: unknown command: %v", errUsage, cmd)
+	}
+}
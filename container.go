@@ -0,0 +1,132 @@
+package dktest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Container is a container started by Start. The container is running until it's stopped by Stop.
+// Container is safe for concurrent use, so the container can be shared by multiple tests. e.g. subtests or all of the
+// tests in a package when started from TestMain
+type Container struct {
+	info        ContainerInfo
+	lgr         Logger
+	dc          Client
+	closeClient func() error
+	netName     string
+	opts        Options
+
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// Start pulls the Docker image and starts a container that's running until Stop is called. Start returns once the
+// container is ready. If the container fails to start, any created resources are cleaned up before returning.
+// The container's lifecycle is logged by the Logger specified by the Options.
+func Start(ctx context.Context, imgName string, opts Options) (*Container, error) {
+	lgr := opts.Logger
+	if lgr == nil {
+		lgr = nopLogger{}
+	}
+	return start(ctx, lgr, imgName, opts)
+}
+
+func start(ctx context.Context, lgr Logger, imgName string, opts Options) (_ *Container, retErr error) {
+	dc, closeClient, err := getClient(ctx, lgr, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Docker client: %w", err)
+	}
+
+	startReaper(lgr, dc, opts)
+
+	opts.init()
+	c := &Container{lgr: lgr, dc: dc, closeClient: closeClient, opts: opts}
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		if err := c.cleanup(ctx, true); err != nil {
+			lgr.Log("Failed to clean up container:", err)
+		}
+	}()
+
+	if opts.Network != nil {
+		netCtx, netTimeoutCancelFunc := context.WithTimeout(ctx, opts.Timeout)
+		c.netName, err = createNetwork(netCtx, lgr, dc, opts.CleanupTimeout, keepOnFailure(opts))
+		netTimeoutCancelFunc()
+		if err != nil {
+			return nil, fmt.Errorf("error creating network: %w", err)
+		}
+	}
+
+	c.info, err = startContainer(ctx, lgr, dc, imgName, c.netName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Info gets the ContainerInfo of the running container
+func (c *Container) Info() ContainerInfo {
+	return c.info
+}
+
+// Stop stops and removes the container along with the container's network and, if CleanupImage is specified, image.
+// Reusable containers are kept running. Stop only stops the container once, so subsequent calls return the result of
+// the first call.
+func (c *Container) Stop(ctx context.Context) error {
+	c.stopOnce.Do(func() {
+		c.stopErr = c.stop(ctx)
+	})
+	return c.stopErr
+}
+
+// cleanup stops the container unless the run failed and the container should be kept for debugging
+func (c *Container) cleanup(ctx context.Context, failed bool) error {
+	if !failed || !keepOnFailure(c.opts) || c.info.ID == "" || c.info.reused {
+		return c.Stop(ctx)
+	}
+	c.stopOnce.Do(func() {
+		c.info.logStream.stop()
+		keepContainer(c.lgr, c.info, c.netName, c.opts.CleanupImage)
+		reaperKeep(c.lgr, c.info.ID, c.netName)
+		live.remove(c.info.ID, c.netName)
+		c.stopErr = c.close()
+	})
+	return c.stopErr
+}
+
+func (c *Container) stop(ctx context.Context) error {
+	func() {
+		stopCtx, stopTimeoutCancelFunc := context.WithTimeout(ctx, c.opts.CleanupTimeout)
+		defer stopTimeoutCancelFunc()
+		if c.info.reused {
+			c.info.logStream.stop()
+			c.lgr.Log("Keeping reusable container:", c.info.String())
+			return
+		}
+		if c.info.ID != "" {
+			stopContainer(stopCtx, c.lgr, c.dc, c.info, c.opts.LogStdout, c.opts.LogStderr)
+		}
+		if c.opts.CleanupImage && c.info.ImageName != "" {
+			removeImage(stopCtx, c.lgr, c.dc, c.info.ImageName)
+		}
+	}()
+
+	if c.netName != "" {
+		removeCtx, removeTimeoutCancelFunc := context.WithTimeout(ctx, c.opts.CleanupTimeout)
+		defer removeTimeoutCancelFunc()
+		removeNetwork(removeCtx, c.lgr, c.dc, c.netName)
+	}
+
+	return c.close()
+}
+
+func (c *Container) close() error {
+	if err := c.closeClient(); err != nil {
+		return fmt.Errorf("error closing Docker client: %w", err)
+	}
+	return nil
+}
@@ -1,11 +1,15 @@
 package dktest
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"strconv"
 )
 
 import (
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 )
 
@@ -104,6 +108,13 @@ type ContainerInfo struct {
 	Name      string
 	ImageName string
 	Ports     nat.PortMap
+
+	// client is the Docker client used to run the container
+	client ContainerAPIClient
+	// reused specifies that the container is reusable and is kept running after the test run
+	reused bool
+	// logStream follows the container's logs if StreamLogs is specified
+	logStream *logStream
 }
 
 // String gets the string representation for the ContainerInfo. This is intended for debugging purposes.
@@ -143,3 +154,21 @@ func (c ContainerInfo) FirstPort() (hostIP string, hostPort string, err error) {
 func (c ContainerInfo) FirstUDPPort() (hostIP string, hostPort string, err error) {
 	return firstPort(c.Ports, "udp")
 }
+
+// Logs gets the container's stdout and stderr logs
+func (c ContainerInfo) Logs(ctx context.Context) (stdout []byte, stderr []byte, err error) {
+	if c.client == nil {
+		return nil, nil, errNoClient
+	}
+	logs, err := c.client.ContainerLogs(ctx, c.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer logs.Close() // nolint:errcheck
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdoutBuf, &stderrBuf, logs); err != nil {
+		return nil, nil, err
+	}
+	return stdoutBuf.Bytes(), stderrBuf.Bytes(), nil
+}
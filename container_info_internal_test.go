@@ -1,9 +1,14 @@
 package dktest
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"strconv"
 	"testing"
 
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/assert"
 )
@@ -150,3 +155,40 @@ func TestPortMapToStrings(t *testing.T) {
 		})
 	}
 }
+
+func TestContainerInfoLogs(t *testing.T) {
+	var logs bytes.Buffer
+	if _, err := stdcopy.NewStdWriter(&logs, stdcopy.Stdout).Write([]byte("out")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdcopy.NewStdWriter(&logs, stdcopy.Stderr).Write([]byte("err")); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name           string
+		client         ContainerAPIClient
+		expectedStdout []byte
+		expectedStderr []byte
+		expectErr      bool
+	}{
+		{name: "no client", client: nil, expectErr: true},
+		{name: "log fetch error", client: &mockdockerclient.ContainerAPIClient{}, expectErr: true},
+		{name: "read error", client: &mockdockerclient.ContainerAPIClient{
+			Logs: mockdockerclient.MockReadCloser{MockReader: mockdockerclient.MockReader{Err: mockdockerclient.Err}},
+		}, expectErr: true},
+		{name: "success", client: &mockdockerclient.ContainerAPIClient{
+			Logs: io.NopCloser(bytes.NewReader(logs.Bytes())),
+		}, expectedStdout: []byte("out"), expectedStderr: []byte("err"), expectErr: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := ContainerInfo{client: tc.client}
+			stdout, stderr, err := c.Logs(context.Background())
+			testErr(t, err, tc.expectErr)
+			assert.Equal(t, tc.expectedStdout, stdout)
+			assert.Equal(t, tc.expectedStderr, stderr)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package dktest
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStart(t *testing.T) {
+	successPullResp := mockdockerclient.MockReadCloser{MockReader: mockdockerclient.MockReader{Err: io.EOF}}
+	successCreateResp := &container.CreateResponse{ID: "containerID"}
+
+	testCases := []struct {
+		name      string
+		client    mockdockerclient.Client
+		opts      Options
+		expectErr bool
+	}{
+		{name: "success", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+			ImageAPIClient:     mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}, opts: Options{ReadyFunc: alwaysReady}, expectErr: false},
+		{name: "success - with network", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+			ImageAPIClient:     mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+			NetworkAPIClient: mockdockerclient.NetworkAPIClient{
+				CreateResp: &network.CreateResponse{ID: "networkID"}},
+		}, opts: Options{ReadyFunc: alwaysReady, Network: &NetworkOptions{}}, expectErr: false},
+		{name: "network error", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+			ImageAPIClient:     mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}, opts: Options{ReadyFunc: alwaysReady, Network: &NetworkOptions{}}, expectErr: true},
+		{name: "pull error", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+		}, opts: Options{ReadyFunc: alwaysReady}, expectErr: true},
+		{name: "run error", client: mockdockerclient.Client{
+			ImageAPIClient: mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}, opts: Options{ReadyFunc: alwaysReady}, expectErr: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			opts := tc.opts
+			opts.Client = &client
+			opts.Logger = t
+			c, err := Start(ctx, imageName, opts)
+			testErr(t, err, tc.expectErr)
+			if tc.expectErr {
+				assert.Nil(t, c)
+				return
+			}
+			assert.Equal(t, "containerID", c.Info().ID)
+			testErr(t, c.Stop(ctx), false)
+		})
+	}
+}
+
+func TestContainerStop(t *testing.T) {
+	client := &mockdockerclient.Client{
+		ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: &container.CreateResponse{ID: "containerID"}},
+		ImageAPIClient: mockdockerclient.ImageAPIClient{
+			PullResp: mockdockerclient.MockReadCloser{MockReader: mockdockerclient.MockReader{Err: io.EOF}}},
+	}
+	lgr := &recordingLogger{}
+	ctx := context.Background()
+	c, err := Start(ctx, imageName, Options{Client: client, Logger: lgr})
+	if err != nil {
+		t.Fatal("Error starting container:", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The container can be borrowed concurrently
+			assert.Equal(t, "containerID", c.Info().ID)
+			testErr(t, c.Stop(ctx), false)
+		}()
+	}
+	wg.Wait()
+
+	stops := 0
+	for _, args := range lgr.logs {
+		if args[0] == "Stopped container:" {
+			stops++
+		}
+	}
+	assert.Equal(t, 1, stops)
+}
+
+func TestStartDefaultLogger(t *testing.T) {
+	client := &mockdockerclient.Client{
+		ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: &container.CreateResponse{ID: "containerID"}},
+		ImageAPIClient: mockdockerclient.ImageAPIClient{
+			PullResp: mockdockerclient.MockReadCloser{MockReader: mockdockerclient.MockReader{Err: io.EOF}}},
+	}
+	ctx := context.Background()
+	c, err := Start(ctx, imageName, Options{Client: client})
+	if err != nil {
+		t.Fatal("Error starting container:", err)
+	}
+	assert.IsType(t, nopLogger{}, c.lgr)
+	testErr(t, c.Stop(ctx), false)
+}
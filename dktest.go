@@ -2,38 +2,38 @@ package dktest
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"strings"
 	"testing"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/network"
-	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
 )
 
 var (
 	// DefaultPullTimeout is the default timeout used when pulling images
 	DefaultPullTimeout = time.Minute
+	// DefaultBuildTimeout is the default timeout used when building images
+	DefaultBuildTimeout = 10 * time.Minute
 	// DefaultTimeout is the default timeout used when starting a container and checking if it's ready
 	DefaultTimeout = time.Minute
 	// DefaultReadyTimeout is the default timeout used for each container ready check.
 	// e.g. each invocation of the ReadyFunc
 	DefaultReadyTimeout = 2 * time.Second
+	// DefaultReadyInterval is the default interval between container ready checks
+	DefaultReadyInterval = time.Second
 	// DefaultCleanupTimeout is the default timeout used when stopping and removing a container
 	DefaultCleanupTimeout = 15 * time.Second
 )
 
-const (
-	label = "dktest"
-)
-
-func pullImage(ctx context.Context, lgr Logger, dc client.ImageAPIClient, registryAuth, imgName, platform string) error {
+func pullImage(ctx context.Context, lgr Logger, dc ImageAPIClient, registryAuth, imgName, platform string,
+	progress func(PullProgress)) error {
 	lgr.Log("Pulling image:", imgName)
-	// lgr.Log(dc.ImageList(ctx, types.ImageListOptions{All: true}))
 
 	resp, err := dc.ImagePull(ctx, imgName, image.PullOptions{
 		Platform:     platform,
@@ -48,43 +48,70 @@ func pullImage(ctx context.Context, lgr Logger, dc client.ImageAPIClient, regist
 		}
 	}()
 
-	// Log response
-	b := strings.Builder{}
-	if err := jsonmessage.DisplayJSONMessagesStream(resp, &b, 0, false, nil); err == nil {
-		lgr.Log("Image pull response:", b.String())
-	} else {
-		lgr.Log("Error parsing image pull response:", err)
+	reporter := newPullProgressReporter(lgr, imgName, progress)
+	dec := json.NewDecoder(resp)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("error reading image pull response: %w", err)
+		}
+		if msg.Error != nil {
+			return &PullError{Image: imgName, Message: msg.Error.Message, Code: msg.Error.Code}
+		} else if msg.ErrorMessage != "" {
+			return &PullError{Image: imgName, Message: msg.ErrorMessage}
+		}
+		reporter.handle(msg)
 	}
+	lgr.Log("Pulled image:", imgName)
 
 	return nil
 }
 
-func removeImage(ctx context.Context, lgr Logger, dc client.ImageAPIClient, imgName string) {
+func removeImage(ctx context.Context, lgr Logger, dc ImageAPIClient, imgName string) {
 	lgr.Log("Removing image:", imgName)
+	defaultPullCoordinator.forget(imgName)
 
 	if _, err := dc.ImageRemove(ctx, imgName, image.RemoveOptions{Force: true, PruneChildren: true}); err != nil {
 		lgr.Log("Failed to remove image: ", err.Error())
 	}
 }
 
-func runImage(ctx context.Context, lgr Logger, dc client.ContainerAPIClient, imgName string,
+func runImage(ctx context.Context, lgr Logger, dc ContainerAPIClient, imgName, netName, reuseHash string,
 	opts Options) (ContainerInfo, error) {
-	c := ContainerInfo{Name: genContainerName(), ImageName: imgName}
+	hostConfig := &container.HostConfig{
+		PublishAllPorts: true,
+		PortBindings:    opts.PortBindings,
+		ShmSize:         opts.ShmSize,
+		Mounts:          opts.Mounts,
+	}
+	if netName != "" && opts.Network != nil {
+		hostConfig.NetworkMode = container.NetworkMode(netName)
+	}
+
+	c := ContainerInfo{Name: genContainerName(), ImageName: imgName, client: dc}
+	labels := resourceLabels(lgr)
+	if reuseHash != "" {
+		// Reusable containers outlive the session, so they aren't labelled with the session
+		c.Name = reuseContainerName(reuseHash)
+		labels[ReuseLabel] = reuseHash
+		delete(labels, SessionLabel)
+	}
+	if keepOnFailure(opts) {
+		labels[KeepOnFailureLabel] = "true"
+	}
 	createResp, err := dc.ContainerCreate(ctx, &container.Config{
 		Image:        imgName,
-		Labels:       map[string]string{label: "true"},
+		Labels:       labels,
 		Env:          opts.env(),
 		Entrypoint:   opts.Entrypoint,
 		Cmd:          opts.Cmd,
 		Volumes:      opts.volumes(),
 		Hostname:     opts.Hostname,
 		ExposedPorts: opts.ExposedPorts,
-	}, &container.HostConfig{
-		PublishAllPorts: true,
-		PortBindings:    opts.PortBindings,
-		ShmSize:         opts.ShmSize,
-		Mounts:          opts.Mounts,
-	}, &network.NetworkingConfig{},
+		Healthcheck:  opts.Healthcheck,
+	}, hostConfig, networkingConfig(netName, opts),
 		nil,
 		c.Name)
 	if err != nil {
@@ -92,11 +119,26 @@ func runImage(ctx context.Context, lgr Logger, dc client.ContainerAPIClient, img
 	}
 	c.ID = createResp.ID
 	lgr.Log("Created container:", c.String())
+	if reuseHash == "" {
+		live.addContainer(liveContainer{lgr: lgr, dc: dc, info: c, cleanupTimeout: opts.CleanupTimeout,
+			logStdout: opts.LogStdout, logStderr: opts.LogStderr})
+	}
+
+	if len(opts.Files) > 0 {
+		if err := copyFiles(ctx, lgr, dc, c, opts.Files); err != nil {
+			return c, fmt.Errorf("error copying files to container: %w", err)
+		}
+	}
 
 	if err := dc.ContainerStart(ctx, createResp.ID, container.StartOptions{}); err != nil {
 		return c, err
 	}
 	lgr.Log("Started container:", c.String())
+	if opts.StreamLogs {
+		c.logStream = streamLogs(lgr, dc, c)
+		// The signal cleanup stops the log stream along with the container
+		live.updateContainer(c)
+	}
 
 	if !opts.PortRequired {
 		return c, nil
@@ -116,8 +158,10 @@ func runImage(ctx context.Context, lgr Logger, dc client.ContainerAPIClient, img
 	return c, nil
 }
 
-func stopContainer(ctx context.Context, lgr Logger, dc client.ContainerAPIClient, c ContainerInfo,
+func stopContainer(ctx context.Context, lgr Logger, dc ContainerAPIClient, c ContainerInfo,
 	logStdout, logStderr bool) {
+	live.remove(c.ID)
+
 	if logStdout || logStderr {
 		if logs, err := dc.ContainerLogs(ctx, c.ID, container.LogsOptions{
 			Timestamps: true, ShowStdout: logStdout, ShowStderr: logStderr,
@@ -140,8 +184,12 @@ func stopContainer(ctx context.Context, lgr Logger, dc client.ContainerAPIClient
 
 	if err := dc.ContainerStop(ctx, c.ID, container.StopOptions{}); err != nil {
 		lgr.Log("Error stopping container:", c.String(), "error:", err)
+		// The log stream doesn't end while the container is still running
+		c.logStream.stop()
 	}
 	lgr.Log("Stopped container:", c.String())
+	// The log stream ends once the container has stopped
+	c.logStream.wait(ctx)
 
 	if err := dc.ContainerRemove(ctx, c.ID,
 		container.RemoveOptions{RemoveVolumes: true, Force: true}); err != nil {
@@ -150,90 +198,184 @@ func stopContainer(ctx context.Context, lgr Logger, dc client.ContainerAPIClient
 	lgr.Log("Removed container:", c.String())
 }
 
-func waitContainerReady(ctx context.Context, lgr Logger, c ContainerInfo,
-	readyFunc func(context.Context, ContainerInfo) bool, readyTimeout time.Duration) bool {
-	if readyFunc == nil {
-		return true
+// waitContainerReady waits for the container to be ready. A container is ready once the ReadyFunc returns true and,
+// if WaitForHealthy is specified, the container's healthcheck reports that the container is healthy.
+// If the container exits before it's ready, a *ContainerExitError is returned.
+func waitContainerReady(ctx context.Context, lgr Logger, dc ContainerAPIClient, c ContainerInfo, opts Options) error {
+	if opts.ReadyFunc == nil && !opts.WaitForHealthy {
+		return nil
 	}
 
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+	waitCtx, waitCancelFunc := context.WithCancel(ctx)
+	defer waitCancelFunc()
+	waitCh, waitErrCh := dc.ContainerWait(waitCtx, c.ID, container.WaitConditionNotRunning)
+
+	// The first ready check is run immediately
+	interval := opts.ReadyInterval
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			ready := func() bool {
-				readyCtx, canceledFunc := context.WithTimeout(ctx, readyTimeout)
+		case waitResp := <-waitCh:
+			exitErr := containerExitError(ctx, lgr, dc, c, int(waitResp.StatusCode))
+			lgr.Log("Container exited before it was ready:", c.String(), "exit code:", exitErr.ExitCode)
+			return exitErr
+		case err := <-waitErrCh:
+			// Fallback to only checking if the container is ready
+			lgr.Log("Error waiting for container to exit:", c.String(), "error:", err)
+			waitCh, waitErrCh = nil, nil
+		case <-timer.C:
+			ready, err := func() (bool, error) {
+				readyCtx, canceledFunc := context.WithTimeout(ctx, opts.ReadyTimeout)
 				defer canceledFunc()
-				return readyFunc(readyCtx, c)
+				if opts.WaitForHealthy {
+					if healthy, err := containerHealthy(readyCtx, lgr, dc, c); !healthy || err != nil {
+						return false, err
+					}
+				}
+				if opts.ReadyFunc == nil {
+					return true, nil
+				}
+				return opts.ReadyFunc(readyCtx, c), nil
 			}()
 
+			if err != nil {
+				lgr.Log("Container will never be ready:", c.String(), "error:", err)
+				return err
+			}
 			if ready {
-				return true
+				return nil
 			}
+			timer.Reset(opts.ReadyBackoff.jitter(interval))
+			interval = opts.ReadyBackoff.next(interval)
 		case <-ctx.Done():
 			lgr.Log("Container was never ready:", c.String())
-			return false
+			return errNotReady
 		}
 	}
 }
 
+// startContainer pulls or builds the image, runs it in a container, and waits for the container to be ready.
+// If a container was created, the returned ContainerInfo will have its ID set even if an error is returned,
+// so the caller is responsible for stopping the container. Similarly, the returned ContainerInfo will have its
+// ImageName set if the image is available, so the caller is responsible for removing the image.
+// Panics, e.g. from the ReadyFunc, are returned as errors.
+// If a network name is given, the container is attached to the network.
+func startContainer(ctx context.Context, lgr Logger, dc Client, imgName, netName string,
+	opts Options) (c ContainerInfo, retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = fmt.Errorf("panic starting container: %v", r)
+		}
+	}()
+
+	var hash string
+	if reuseEnabled(opts) {
+		if opts.Network != nil || opts.Build != nil {
+			return ContainerInfo{}, errReuseUnsupported
+		}
+		var err error
+		if hash, err = reuseHash(imgName, opts); err != nil {
+			return ContainerInfo{}, fmt.Errorf("error hashing container config: %w", err)
+		}
+		if c, err := reuseContainer(ctx, lgr, dc, imgName, hash, opts, false); err != nil || c.ID != "" {
+			return c, err
+		}
+	}
+
+	pullCtx, pullTimeoutCancelFunc := context.WithTimeout(ctx, opts.PullTimeout)
+	defer pullTimeoutCancelFunc()
+
+	switch {
+	case opts.Build != nil:
+		buildCtx, buildTimeoutCancelFunc := context.WithTimeout(ctx, opts.BuildTimeout)
+		builtImgName, err := buildImage(buildCtx, lgr, dc, imgName, opts.Platform, opts.Build)
+		buildTimeoutCancelFunc()
+		if err != nil {
+			return ContainerInfo{}, fmt.Errorf("error building image: %v error: %w", imgName, err)
+		}
+		imgName = builtImgName
+	case opts.ImageArchive != "" || opts.ImageArchiveReader != nil:
+		if err := loadImage(pullCtx, lgr, dc, imgName, opts); err != nil {
+			return ContainerInfo{}, fmt.Errorf("error loading image: %v error: %w", imgName, err)
+		}
+	default:
+		if err := ensureImage(pullCtx, lgr, dc, imgName, opts); err != nil {
+			return ContainerInfo{}, fmt.Errorf("error pulling image: %v error: %w", imgName, err)
+		}
+	}
+
+	runCtx, runTimeoutCancelFunc := context.WithTimeout(ctx, opts.Timeout)
+	defer runTimeoutCancelFunc()
+
+	c, err := runImage(runCtx, lgr, dc, imgName, netName, hash, opts)
+	if err != nil && hash != "" && c.ID == "" && cerrdefs.IsConflict(err) {
+		// Another test or process created the reusable container after it wasn't found, so the container is
+		// reused once it's started
+		lgr.Log("Reusable container was created concurrently:", c.Name)
+		return reuseContainer(ctx, lgr, dc, imgName, hash, opts, true)
+	}
+	if err != nil {
+		return c, fmt.Errorf("error running image: %v error: %w", imgName, err)
+	}
+
+	if err := waitContainerReady(runCtx, lgr, dc, c, opts); err != nil {
+		return c, fmt.Errorf("%w: %v", err, c.String())
+	}
+	c.reused = hash != ""
+
+	return c, nil
+}
+
+// runTestFunc runs the test function and returns errTestFailed if the test failed while the test function was running.
+// A test that had already failed before the test function was run, e.g. from an earlier assertion, doesn't count as
+// a failure of the test function.
+func runTestFunc(t interface{ Failed() bool }, testFunc func()) error {
+	failed := t.Failed()
+	testFunc()
+	if !failed && t.Failed() {
+		return errTestFailed
+	}
+	return nil
+}
+
 // Run runs the given test function once the specified Docker image is running in a container
 func Run(t *testing.T, imgName string, opts Options, testFunc func(*testing.T, ContainerInfo)) {
 	err := RunContext(context.Background(), t, imgName, opts, func(containerInfo ContainerInfo) error {
-		testFunc(t, containerInfo)
-		return nil
+		return runTestFunc(t, func() { testFunc(t, containerInfo) })
 	})
-	if err != nil {
+	if err != nil && !errors.Is(err, errTestFailed) {
 		t.Fatal("Failed:", err)
 	}
 }
 
+// RunWithClient is similar to Run, but uses the given Docker client instead of creating a new one.
+// The client is not closed after the test function is run.
+func RunWithClient(t *testing.T, dc Client, imgName string, opts Options, testFunc func(*testing.T, ContainerInfo)) {
+	opts.Client = dc
+	Run(t, imgName, opts, testFunc)
+}
+
 // RunContext is similar to Run, but takes a parent context and returns an error and doesn't rely on a testing.T.
 func RunContext(ctx context.Context, logger Logger, imgName string, opts Options, testFunc func(ContainerInfo) error) (retErr error) {
-	dc, err := client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.41"))
+	c, err := start(ctx, logger, imgName, opts)
 	if err != nil {
-		return fmt.Errorf("error getting Docker client: %w", err)
+		return err
 	}
+	// failed is only cleared once the test func returns successfully, so a test func that panics or calls
+	// t.FailNow() is also a failure
+	failed := true
 	defer func() {
-		if err := dc.Close(); err != nil && retErr == nil {
-			retErr = fmt.Errorf("error closing Docker client: %w", err)
+		if err := c.cleanup(ctx, failed); err != nil && retErr == nil {
+			retErr = err
 		}
 	}()
 
-	opts.init()
-	pullCtx, pullTimeoutCancelFunc := context.WithTimeout(ctx, opts.PullTimeout)
-	defer pullTimeoutCancelFunc()
-
-	if err := pullImage(pullCtx, logger, dc, opts.PullRegistryAuth, imgName, opts.Platform); err != nil {
-		return fmt.Errorf("error pulling image: %v error: %w", imgName, err)
+	if err := testFunc(c.Info()); err != nil {
+		return fmt.Errorf("error running test func: %w", err)
 	}
+	failed = false
 
-	return func() error {
-		runCtx, runTimeoutCancelFunc := context.WithTimeout(ctx, opts.Timeout)
-		defer runTimeoutCancelFunc()
-
-		c, err := runImage(runCtx, logger, dc, imgName, opts)
-		if err != nil {
-			return fmt.Errorf("error running image: %v error: %w", imgName, err)
-		}
-		defer func() {
-			stopCtx, stopTimeoutCancelFunc := context.WithTimeout(ctx, opts.CleanupTimeout)
-			defer stopTimeoutCancelFunc()
-			stopContainer(stopCtx, logger, dc, c, opts.LogStdout, opts.LogStderr)
-			if opts.CleanupImage {
-				removeImage(stopCtx, logger, dc, imgName)
-			}
-		}()
-
-		if waitContainerReady(runCtx, logger, c, opts.ReadyFunc, opts.ReadyTimeout) {
-			if err := testFunc(c); err != nil {
-				return fmt.Errorf("error running test func: %w", err)
-			}
-		} else {
-			return fmt.Errorf("timed out waiting for container to get ready: %v", c.String())
-		}
-
-		return nil
-	}()
+	return nil
 }
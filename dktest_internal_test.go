@@ -2,13 +2,17 @@ package dktest
 
 import (
 	"context"
+	"errors"
 	"io"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dhui/dktest/mockdockerclient"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
 )
 
 const (
@@ -23,6 +27,39 @@ var (
 func alwaysReady(context.Context, ContainerInfo) bool { return true }
 func neverReady(context.Context, ContainerInfo) bool  { return false }
 
+// readyAfter gets a ready func that's ready after the given number of checks
+func readyAfter(n int) func(context.Context, ContainerInfo) bool {
+	checks := 0
+	return func(context.Context, ContainerInfo) bool {
+		checks++
+		return checks >= n
+	}
+}
+
+// recordingLogger records the logged messages
+type recordingLogger struct {
+	mu   sync.Mutex
+	logs [][]interface{}
+}
+
+func (l *recordingLogger) Log(args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, args)
+}
+
+// logged checks if a message was logged with the given first argument
+func (l *recordingLogger) logged(msg string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, args := range l.logs {
+		if len(args) > 0 && args[0] == msg {
+			return true
+		}
+	}
+	return false
+}
+
 func testErr(t *testing.T, err error, expectErr bool) {
 	t.Helper()
 	if err == nil && expectErr {
@@ -36,10 +73,11 @@ func TestPullImage(t *testing.T) {
 	successReader := mockdockerclient.MockReader{Err: io.EOF}
 
 	testCases := []struct {
-		name      string
-		client    mockdockerclient.ImageAPIClient
-		platform  string
-		expectErr bool
+		name            string
+		client          mockdockerclient.ImageAPIClient
+		platform        string
+		expectedPullErr *PullError
+		expectErr       bool
 	}{
 		{name: "success", client: mockdockerclient.ImageAPIClient{
 			PullResp: mockdockerclient.MockReadCloser{MockReader: successReader}}, expectErr: false},
@@ -50,20 +88,45 @@ func TestPullImage(t *testing.T) {
 		{name: "read error", client: mockdockerclient.ImageAPIClient{
 			PullResp: mockdockerclient.MockReadCloser{
 				MockReader: mockdockerclient.MockReader{Err: mockdockerclient.Err},
-			}}, expectErr: false},
+			}}, expectErr: true},
 		{name: "close error", client: mockdockerclient.ImageAPIClient{
 			PullResp: mockdockerclient.MockReadCloser{
 				MockReader: successReader,
 				MockCloser: mockdockerclient.MockCloser{Err: mockdockerclient.Err},
 			}}, expectErr: false},
+		{name: "success - with progress", client: mockdockerclient.ImageAPIClient{
+			PullResp: io.NopCloser(strings.NewReader(`{"status":"Pulling from library/postgres","id":"16"}
+{"status":"Downloading","progressDetail":{"current":1024,"total":2048},"id":"0123456789ab"}
+{"status":"Pull complete","progressDetail":{},"id":"0123456789ab"}`))}, expectErr: false},
+		{name: "error detail", client: mockdockerclient.ImageAPIClient{
+			PullResp: io.NopCloser(strings.NewReader(`{"status":"Pulling from library/postgres","id":"16"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`))},
+			expectedPullErr: &PullError{Image: imageName, Message: "manifest unknown"}, expectErr: true},
+		{name: "error detail with code", client: mockdockerclient.ImageAPIClient{
+			PullResp: io.NopCloser(strings.NewReader(
+				`{"errorDetail":{"code":401,"message":"denied: requested access to the resource is denied"}}`))},
+			expectedPullErr: &PullError{Image: imageName,
+				Message: "denied: requested access to the resource is denied", Code: 401}, expectErr: true},
+		{name: "error message", client: mockdockerclient.ImageAPIClient{
+			PullResp: io.NopCloser(strings.NewReader(`{"error":"manifest unknown"}`))},
+			expectedPullErr: &PullError{Image: imageName, Message: "manifest unknown"}, expectErr: true},
+		{name: "malformed response", client: mockdockerclient.ImageAPIClient{
+			PullResp: io.NopCloser(strings.NewReader(`{`))}, expectErr: true},
 	}
 
 	ctx := context.Background()
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			client := tc.client
-			err := pullImage(ctx, t, &client, "", imageName, tc.platform)
+			err := pullImage(ctx, t, &client, "", imageName, tc.platform, nil)
 			testErr(t, err, tc.expectErr)
+			if tc.expectedPullErr != nil {
+				var pullErr *PullError
+				if !errors.As(err, &pullErr) {
+					t.Fatal("Expected a PullError but got:", err)
+				}
+				assert.Equal(t, tc.expectedPullErr, pullErr)
+			}
 		})
 	}
 }
@@ -97,11 +160,15 @@ func TestRunImage(t *testing.T) {
 	testCases := []struct {
 		name      string
 		client    mockdockerclient.ContainerAPIClient
+		netName   string
 		opts      Options
 		expectErr bool
 	}{
 		{name: "success", client: mockdockerclient.ContainerAPIClient{
 			CreateResp: successCreateResp, InspectResp: successInspectResp}, expectErr: false},
+		{name: "success - with network", client: mockdockerclient.ContainerAPIClient{
+			CreateResp: successCreateResp, InspectResp: successInspectResp}, netName: "dktest_network",
+			opts: Options{Network: &NetworkOptions{Aliases: []string{"db"}}}, expectErr: false},
 		{name: "success - with port binding no ip", client: mockdockerclient.ContainerAPIClient{
 			CreateResp: successCreateResp, InspectResp: successInspectRespWithPortBindingNoIP}, expectErr: false},
 		{name: "success - with port binding ip 0.0.0.0", client: mockdockerclient.ContainerAPIClient{
@@ -115,6 +182,12 @@ func TestRunImage(t *testing.T) {
 		}, expectErr: true},
 		{name: "inspect error", client: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
 			opts: Options{PortRequired: true}, expectErr: true},
+		{name: "success - with files", client: mockdockerclient.ContainerAPIClient{
+			CreateResp: successCreateResp, InspectResp: successInspectResp},
+			opts: Options{Files: []File{{ContainerPath: "/seed.sql", Content: []byte("SELECT 1;")}}}, expectErr: false},
+		{name: "copy files error", client: mockdockerclient.ContainerAPIClient{
+			CreateResp: successCreateResp, InspectResp: successInspectResp, CopyToErr: mockdockerclient.Err},
+			opts: Options{Files: []File{{ContainerPath: "/seed.sql", Content: []byte("SELECT 1;")}}}, expectErr: true},
 		{name: "no network settings error", client: mockdockerclient.ContainerAPIClient{
 			CreateResp: successCreateResp, InspectResp: successInspectResp}, opts: Options{PortRequired: true},
 			expectErr: true},
@@ -124,7 +197,7 @@ func TestRunImage(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			client := tc.client
-			_, err := runImage(ctx, t, &client, imageName, tc.opts)
+			_, err := runImage(ctx, t, &client, imageName, tc.netName, "", tc.opts)
 			testErr(t, err, tc.expectErr)
 		})
 	}
@@ -164,28 +237,177 @@ func TestStopContainer(t *testing.T) {
 	}
 }
 
+// failedTB is a test whose failure state can be set
+type failedTB struct{ failed bool }
+
+func (tb *failedTB) Failed() bool { return tb.failed }
+
+func TestRunTestFunc(t *testing.T) {
+	testCases := []struct {
+		name          string
+		failedBefore  bool
+		failsDuring   bool
+		expectedError error
+	}{
+		{name: "passed", failedBefore: false, failsDuring: false, expectedError: nil},
+		{name: "failed during test func", failedBefore: false, failsDuring: true, expectedError: errTestFailed},
+		{name: "failed before test func", failedBefore: true, failsDuring: false, expectedError: nil},
+		{name: "failed before and during test func", failedBefore: true, failsDuring: true, expectedError: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tb := &failedTB{failed: tc.failedBefore}
+			err := runTestFunc(tb, func() { tb.failed = tb.failed || tc.failsDuring })
+			assert.Equal(t, tc.expectedError, err)
+		})
+	}
+}
+
 func TestWaitContainerReady(t *testing.T) {
 	canceledCtx, cancelFunc := context.WithCancel(context.Background())
 	cancelFunc()
 
+	inspectResp := func(state *container.State) *container.InspectResponse {
+		return &container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{State: state}}
+	}
+	healthyClient := &mockdockerclient.ContainerAPIClient{InspectResp: inspectResp(&container.State{
+		Running: true, Health: &container.Health{Status: container.Healthy}})}
+	startingClient := &mockdockerclient.ContainerAPIClient{InspectResp: inspectResp(&container.State{
+		Running: true, Health: &container.Health{Status: container.Starting}})}
+	unhealthyClient := &mockdockerclient.ContainerAPIClient{InspectResp: inspectResp(&container.State{
+		Running: true, Health: &container.Health{Status: container.Unhealthy, Log: []*container.HealthcheckResult{
+			{ExitCode: 1, Output: "connection refused"},
+		}}})}
+	noHealthcheckClient := &mockdockerclient.ContainerAPIClient{InspectResp: inspectResp(&container.State{
+		Running: true})}
+	exitedClient := &mockdockerclient.ContainerAPIClient{InspectResp: inspectResp(&container.State{
+		Status: "exited", ExitCode: 1})}
+	waitExitedClient := &mockdockerclient.ContainerAPIClient{
+		InspectResp: inspectResp(&container.State{Status: "exited", ExitCode: 137, OOMKilled: true}),
+		WaitResp:    &container.WaitResponse{StatusCode: 137},
+	}
+	waitErrClient := &mockdockerclient.ContainerAPIClient{WaitErr: mockdockerclient.Err}
+
 	testCases := []struct {
 		name        string
 		ctx         context.Context
-		readyFunc   func(context.Context, ContainerInfo) bool
-		expectReady bool
+		client      ContainerAPIClient
+		opts        Options
+		expectedErr error
+		expectExit  bool
 	}{
-		{name: "nil readyFunc", ctx: canceledCtx, readyFunc: nil, expectReady: true},
-		{name: "ready", ctx: context.Background(), readyFunc: alwaysReady, expectReady: true},
-		{name: "not ready", ctx: canceledCtx, readyFunc: neverReady, expectReady: false},
+		{name: "nil readyFunc", ctx: canceledCtx, opts: Options{ReadyFunc: nil}, expectedErr: nil},
+		{name: "ready", ctx: context.Background(), opts: Options{ReadyFunc: alwaysReady}, expectedErr: nil},
+		{name: "not ready", ctx: canceledCtx, opts: Options{ReadyFunc: neverReady}, expectedErr: errNotReady},
+		{name: "healthy", ctx: context.Background(), client: healthyClient, opts: Options{WaitForHealthy: true},
+			expectedErr: nil},
+		{name: "healthy - ready", ctx: context.Background(), client: healthyClient,
+			opts: Options{WaitForHealthy: true, ReadyFunc: alwaysReady}, expectedErr: nil},
+		{name: "healthy - not ready", ctx: canceledCtx, client: healthyClient,
+			opts: Options{WaitForHealthy: true, ReadyFunc: neverReady}, expectedErr: errNotReady},
+		{name: "starting", ctx: canceledCtx, client: startingClient, opts: Options{WaitForHealthy: true},
+			expectedErr: errNotReady},
+		{name: "unhealthy", ctx: context.Background(), client: unhealthyClient,
+			opts: Options{WaitForHealthy: true, ReadyFunc: alwaysReady}, expectedErr: errContainerUnhealthy},
+		{name: "no healthcheck", ctx: context.Background(), client: noHealthcheckClient,
+			opts: Options{WaitForHealthy: true}, expectedErr: errNoHealthcheck},
+		{name: "exited - healthcheck", ctx: context.Background(), client: exitedClient,
+			opts: Options{WaitForHealthy: true}, expectExit: true},
+		{name: "exited - wait", ctx: context.Background(), client: waitExitedClient,
+			opts: Options{ReadyFunc: neverReady}, expectExit: true},
+		{name: "wait error", ctx: context.Background(), client: waitErrClient, opts: Options{ReadyFunc: alwaysReady},
+			expectedErr: nil},
+		{name: "ready after retries", ctx: context.Background(), opts: Options{ReadyFunc: readyAfter(3),
+			ReadyBackoff: &Backoff{Multiplier: 1.5, MaxInterval: 20 * time.Millisecond, Jitter: 0.1}},
+			expectedErr: nil},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			if ready := waitContainerReady(tc.ctx, t, containerInfo, tc.readyFunc,
-				time.Second); ready && !tc.expectReady {
-				t.Error("Expected container to not be ready but it was")
-			} else if !ready && tc.expectReady {
-				t.Error("Expected container to ready but it wasn't")
+			client := tc.client
+			if client == nil {
+				client = &mockdockerclient.ContainerAPIClient{}
+			}
+			tc.opts.ReadyTimeout = time.Second
+			tc.opts.ReadyInterval = 10 * time.Millisecond
+			err := waitContainerReady(tc.ctx, t, client, containerInfo, tc.opts)
+			var exitErr *ContainerExitError
+			if tc.expectExit {
+				if !errors.As(err, &exitErr) {
+					t.Error("Expected a ContainerExitError, got error:", err)
+				}
+			} else if !errors.Is(err, tc.expectedErr) {
+				t.Error("Got unexpected error:", err, "!=", tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRunContextWithClient(t *testing.T) {
+	successPullResp := mockdockerclient.MockReadCloser{MockReader: mockdockerclient.MockReader{Err: io.EOF}}
+	successCreateResp := &container.CreateResponse{ID: "containerID"}
+	successClient := mockdockerclient.Client{
+		ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+		ImageAPIClient:     mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+	}
+	errForTest := errors.New("testFunc failed")
+
+	testCases := []struct {
+		name          string
+		client        mockdockerclient.Client
+		readyFunc     func(context.Context, ContainerInfo) bool
+		streamLogs    bool
+		testFuncErr   error
+		expectPullErr bool
+		expectErr     bool
+	}{
+		{name: "success", client: successClient, readyFunc: alwaysReady, expectErr: false},
+		{name: "success - stream logs", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp,
+				Logs: io.NopCloser(strings.NewReader(""))},
+			ImageAPIClient: mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}, readyFunc: alwaysReady, streamLogs: true, expectErr: false},
+		{name: "pull error", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+		}, readyFunc: alwaysReady, expectErr: true},
+		{name: "pull stream error", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+			ImageAPIClient: mockdockerclient.ImageAPIClient{PullResp: io.NopCloser(strings.NewReader(
+				`{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`))},
+		}, readyFunc: alwaysReady, expectPullErr: true, expectErr: true},
+		{name: "run error", client: mockdockerclient.Client{
+			ImageAPIClient: mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}, readyFunc: alwaysReady, expectErr: true},
+		{name: "not ready", client: successClient, readyFunc: neverReady, expectErr: true},
+		{name: "test func error", client: successClient, readyFunc: alwaysReady, testFuncErr: errForTest,
+			expectErr: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			ran := false
+			err := RunContext(ctx, t, imageName, Options{
+				Client:     &client,
+				ReadyFunc:  tc.readyFunc,
+				Timeout:    2 * time.Second,
+				StreamLogs: tc.streamLogs,
+			}, func(ContainerInfo) error {
+				ran = true
+				return tc.testFuncErr
+			})
+			testErr(t, err, tc.expectErr)
+			var pullErr *PullError
+			if errors.As(err, &pullErr) != tc.expectPullErr {
+				t.Error("Unexpected PullError, got error:", err)
+			}
+			if tc.testFuncErr != nil && !errors.Is(err, tc.testFuncErr) {
+				t.Error("test func error not propagated, got error:", err)
+			}
+			if ran != (tc.testFuncErr != nil || !tc.expectErr) {
+				t.Error("Test func unexpectedly ran:", ran)
 			}
 		})
 	}
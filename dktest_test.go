@@ -111,3 +111,60 @@ func TestRunWithExposesPorts(t *testing.T) {
 		}
 	})
 }
+
+func TestRunGroup(t *testing.T) {
+	dktest.RunGroup(t, []dktest.ContainerSpec{
+		{Name: "alpine", ImageName: testImage},
+		{Name: "nginx", ImageName: testNetworkImage, Options: dktest.Options{ReadyFunc: nginxReady, PortRequired: true}},
+	}, func(t *testing.T, containers map[string]dktest.ContainerInfo) {
+		if len(containers) != 2 {
+			t.Fatal("Got wrong number of containers:", len(containers))
+		}
+		if _, _, err := containers["nginx"].FirstPort(); err != nil {
+			t.Fatal("nginx port not mapped:", err)
+		}
+	})
+}
+
+func TestRunGroupWithNetwork(t *testing.T) {
+	dktest.RunGroup(t, []dktest.ContainerSpec{
+		{Name: "web", ImageName: testNetworkImage, Options: dktest.Options{
+			ReadyFunc: nginxReady, PortRequired: true, Network: &dktest.NetworkOptions{Aliases: []string{"nginx"}},
+		}},
+		{Name: "client", ImageName: testImage, Options: dktest.Options{
+			Cmd: []string{"sleep", "60"}, Network: &dktest.NetworkOptions{},
+		}},
+	}, func(t *testing.T, containers map[string]dktest.ContainerInfo) {
+		if len(containers) != 2 {
+			t.Fatal("Got wrong number of containers:", len(containers))
+		}
+		// The web container is reachable from the client container by its spec name and its aliases
+		for _, host := range []string{"web", "nginx"} {
+			_, stderr, exitCode, err := containers["client"].Exec(context.Background(),
+				[]string{"wget", "-q", "-T", "5", "-O", "/dev/null", "http://" + host + "/"}, dktest.ExecOptions{})
+			if err != nil {
+				t.Fatal("Exec failed:", err)
+			}
+			if exitCode != 0 {
+				t.Errorf("Failed to reach %v from the client container. exit code: %v stderr: %s", host, exitCode,
+					stderr)
+			}
+		}
+	})
+}
+
+func TestRunExec(t *testing.T) {
+	dktest.Run(t, testImage, dktest.Options{Cmd: []string{"sleep", "60"}},
+		func(t *testing.T, c dktest.ContainerInfo) {
+			stdout, _, exitCode, err := c.Exec(context.Background(), []string{"echo", "hello"}, dktest.ExecOptions{})
+			if err != nil {
+				t.Fatal("Exec failed:", err)
+			}
+			if exitCode != 0 {
+				t.Error("Unexpected exit code:", exitCode)
+			}
+			if string(stdout) != "hello\n" {
+				t.Errorf("Unexpected stdout: %q", stdout)
+			}
+		})
+}
@@ -2,9 +2,57 @@ package dktest
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
-	errNoNetworkSettings = errors.New("no network settings")
-	errNoPort            = errors.New("no port")
+	errNoNetworkSettings  = errors.New("no network settings")
+	errNoPort             = errors.New("no port")
+	errNoSpecs            = errors.New("no container specs")
+	errNoSpecName         = errors.New("container spec has no name")
+	errGroupClient        = errors.New("container specs must have the same Client and DockerAPIVersion")
+	errNoClient           = errors.New("no Docker client")
+	errNotReady           = errors.New("timed out waiting for container to get ready")
+	errNoHealthcheck      = errors.New("container has no healthcheck")
+	errContainerUnhealthy = errors.New("container is unhealthy")
+	errFileNotAbs         = errors.New("file container path is not absolute")
+	errFileSource         = errors.New("file must have exactly one of Content, HostPath, or FS")
+	errFileType           = errors.New("file is not a regular file or directory")
+	errFileOutsideDir     = errors.New("file is outside of the directory")
+	errBuildContext       = errors.New("build must have exactly one of ContextDir or ContextFS")
+	errImageNotPresent    = errors.New("image is not present")
+	errInvalidPullPolicy  = errors.New("invalid pull policy")
+	errPullAborted        = errors.New("image pull aborted")
+	errImageArchive       = errors.New("only one of ImageArchive or ImageArchiveReader can be specified")
+	errImageNotLoaded     = errors.New("image archive doesn't contain image")
+	errReuseUnsupported   = errors.New("reuse isn't supported with Network or Build")
+	errTestFailed         = errors.New("test failed")
 )
+
+// ContainerExitError is returned when a container exits before it's ready
+type ContainerExitError struct {
+	Container ContainerInfo
+	ExitCode  int
+	OOMKilled bool
+	// Logs are the last lines of the container's stdout and stderr logs
+	Logs string
+}
+
+func (e *ContainerExitError) Error() string {
+	return fmt.Sprintf("container exited before it was ready: %v exit code: %d OOM killed: %t logs:\n%s",
+		e.Container.String(), e.ExitCode, e.OOMKilled, e.Logs)
+}
+
+// PullError is returned when the Docker daemon reports an error while pulling an image.
+// e.g. when the image's manifest is unknown or access to the image's repository is denied
+type PullError struct {
+	Image string
+	// Message is the error message reported by the registry or Docker daemon. e.g. "manifest unknown"
+	Message string
+	// Code is the error code reported by the Docker daemon. Zero if no code was reported.
+	Code int
+}
+
+func (e *PullError) Error() string {
+	return fmt.Sprintf("image pull failed: %v message: %v", e.Image, e.Message)
+}
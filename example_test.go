@@ -5,36 +5,18 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
-	"net/url"
 	"testing"
 )
 
 import (
 	"github.com/dhui/dktest"
+	"github.com/dhui/dktest/ready"
 	_ "github.com/lib/pq"
 )
 
 func Example_nginx() {
 	dockerImageName := "nginx:alpine"
-	readyFunc := func(ctx context.Context, c dktest.ContainerInfo) bool {
-		ip, port, err := c.FirstPort()
-		if err != nil {
-			return false
-		}
-		u := url.URL{Scheme: "http", Host: ip + ":" + port}
-		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
-		if err != nil {
-			fmt.Println(err)
-			return false
-		}
-		req = req.WithContext(ctx)
-		if resp, err := http.DefaultClient.Do(req); err != nil {
-			return false
-		} else if resp.StatusCode != 200 {
-			return false
-		}
-		return true
-	}
+	readyFunc := ready.HTTP(80, "/", http.StatusOK)
 
 	// dktest.Run() should be used within a test
 	dktest.Run(&testing.T{}, dockerImageName, dktest.Options{PortRequired: true, ReadyFunc: readyFunc},
@@ -85,3 +67,25 @@ func Example_postgres() {
 
 	// Output:
 }
+
+func ExampleStart() {
+	// dktest.Start() can be used in TestMain to share a container between all of the tests in a package
+	ctx := context.Background()
+	c, err := dktest.Start(ctx, "nginx:alpine", dktest.Options{
+		PortRequired: true,
+		ReadyFunc:    ready.HTTP(80, "/", http.StatusOK),
+	})
+	if err != nil {
+		fmt.Println("Failed to start container:", err)
+		return
+	}
+	defer c.Stop(ctx) // nolint:errcheck
+
+	// Tests, including parallel subtests, can use the container
+	ip, port, err := c.Info().FirstPort()
+	if err != nil {
+		fmt.Println("Failed to get port:", err)
+		return
+	}
+	fmt.Println("nginx is listening on", ip+":"+port)
+}
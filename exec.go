@@ -0,0 +1,110 @@
+package dktest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// execInspectInterval is the initial interval between checks for whether an exec'd command has exited
+	execInspectInterval = 10 * time.Millisecond
+	// execInspectMaxInterval is the maximum interval between checks for whether an exec'd command has exited
+	execInspectMaxInterval = 500 * time.Millisecond
+)
+
+// ExecOptions contains the configurable options for executing a command in a container
+type ExecOptions struct {
+	Env        map[string]string
+	WorkingDir string
+	User       string
+	// Stdin is streamed to the command's stdin if specified
+	Stdin io.Reader
+}
+
+// Exec executes the command in the running container and waits for the command to exit.
+// A non-zero exit code is not treated as an error.
+func (c ContainerInfo) Exec(ctx context.Context, cmd []string, opts ExecOptions) (stdout []byte, stderr []byte,
+	exitCode int, err error) {
+	if c.client == nil {
+		return nil, nil, 0, errNoClient
+	}
+
+	createResp, err := c.client.ContainerExecCreate(ctx, c.ID, container.ExecOptions{
+		User:         opts.User,
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          envList(opts.Env),
+		WorkingDir:   opts.WorkingDir,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	attachResp, err := c.client.ContainerExecAttach(ctx, createResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer attachResp.Close()
+
+	// The attached connection doesn't respect the context, so close it if the context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			attachResp.Close()
+		case <-done:
+		}
+	}()
+
+	if opts.Stdin != nil {
+		go func() {
+			_, _ = io.Copy(attachResp.Conn, opts.Stdin)
+			_ = attachResp.CloseWrite()
+		}()
+	}
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdoutBuf, &stderrBuf, attachResp.Reader); err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, 0, ctx.Err()
+		}
+		return nil, nil, 0, err
+	}
+
+	exitCode, err = waitExit(ctx, c.client, createResp.ID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return stdoutBuf.Bytes(), stderrBuf.Bytes(), exitCode, nil
+}
+
+// waitExit waits for the exec'd command to exit and returns the command's exit code. The attached streams can be
+// closed before the command exits, so the exec is inspected until it's no longer running.
+func waitExit(ctx context.Context, dc ContainerAPIClient, execID string) (int, error) {
+	backoff := &Backoff{MaxInterval: execInspectMaxInterval}
+	interval := execInspectInterval
+	for {
+		inspectResp, err := dc.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, err
+		}
+		if !inspectResp.Running {
+			return inspectResp.ExitCode, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = backoff.next(interval)
+	}
+}
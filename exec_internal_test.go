@@ -0,0 +1,99 @@
+package dktest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+)
+
+// runningExecClient is a mock client whose exec is running for the given number of inspects before exiting
+type runningExecClient struct {
+	*mockdockerclient.ContainerAPIClient
+	running int
+}
+
+func (c *runningExecClient) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	resp, err := c.ContainerAPIClient.ContainerExecInspect(ctx, execID)
+	if c.running != 0 {
+		c.running--
+		resp.Running, resp.ExitCode = true, 0
+	}
+	return resp, err
+}
+
+func TestContainerInfoExec(t *testing.T) {
+	var output bytes.Buffer
+	if _, err := stdcopy.NewStdWriter(&output, stdcopy.Stdout).Write([]byte("out")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdcopy.NewStdWriter(&output, stdcopy.Stderr).Write([]byte("err")); err != nil {
+		t.Fatal(err)
+	}
+
+	successCreateResp := &container.ExecCreateResponse{ID: "execID"}
+	successInspectResp := &container.ExecInspect{ExecID: "execID", ExitCode: 3}
+
+	testCases := []struct {
+		name             string
+		client           ContainerAPIClient
+		opts             ExecOptions
+		expectedStdout   []byte
+		expectedStderr   []byte
+		expectedExitCode int
+		// timeout is the timeout of the context used to exec the command if specified
+		timeout   time.Duration
+		expectErr bool
+	}{
+		{name: "no client", client: nil, expectErr: true},
+		{name: "create error", client: &mockdockerclient.ContainerAPIClient{}, expectErr: true},
+		{name: "attach error", client: &mockdockerclient.ContainerAPIClient{ExecCreateResp: successCreateResp},
+			expectErr: true},
+		{name: "read error", client: &mockdockerclient.ContainerAPIClient{
+			ExecCreateResp: successCreateResp, ExecOutput: []byte("not multiplexed"),
+			ExecInspectResp: successInspectResp,
+		}, expectErr: true},
+		{name: "inspect error", client: &mockdockerclient.ContainerAPIClient{
+			ExecCreateResp: successCreateResp, ExecOutput: output.Bytes(),
+		}, expectErr: true},
+		{name: "success", client: &mockdockerclient.ContainerAPIClient{
+			ExecCreateResp: successCreateResp, ExecOutput: output.Bytes(), ExecInspectResp: successInspectResp,
+		}, expectedStdout: []byte("out"), expectedStderr: []byte("err"), expectedExitCode: 3, expectErr: false},
+		{name: "success - with options", client: &mockdockerclient.ContainerAPIClient{
+			ExecCreateResp: successCreateResp, ExecOutput: output.Bytes(), ExecInspectResp: successInspectResp,
+		}, opts: ExecOptions{Env: map[string]string{"foo": "bar"}, WorkingDir: "/", User: "root",
+			Stdin: strings.NewReader("in")},
+			expectedStdout: []byte("out"), expectedStderr: []byte("err"), expectedExitCode: 3, expectErr: false},
+		{name: "success - still running after output", client: &runningExecClient{
+			ContainerAPIClient: &mockdockerclient.ContainerAPIClient{ExecCreateResp: successCreateResp,
+				ExecOutput: output.Bytes(), ExecInspectResp: successInspectResp}, running: 2},
+			expectedStdout: []byte("out"), expectedStderr: []byte("err"), expectedExitCode: 3, expectErr: false},
+		{name: "never exits", client: &runningExecClient{
+			ContainerAPIClient: &mockdockerclient.ContainerAPIClient{ExecCreateResp: successCreateResp,
+				ExecOutput: output.Bytes(), ExecInspectResp: successInspectResp}, running: -1},
+			timeout: 50 * time.Millisecond, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancelFunc context.CancelFunc
+				ctx, cancelFunc = context.WithTimeout(ctx, tc.timeout)
+				defer cancelFunc()
+			}
+			c := ContainerInfo{client: tc.client}
+			stdout, stderr, exitCode, err := c.Exec(ctx, []string{"echo"}, tc.opts)
+			testErr(t, err, tc.expectErr)
+			assert.Equal(t, tc.expectedStdout, stdout)
+			assert.Equal(t, tc.expectedStderr, stderr)
+			assert.Equal(t, tc.expectedExitCode, exitCode)
+		})
+	}
+}
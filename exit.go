@@ -0,0 +1,49 @@
+package dktest
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// exitLogLines is the number of log lines included in a ContainerExitError
+	exitLogLines = 50
+)
+
+// tailLogs gets the last n lines of the container's stdout and stderr logs
+func tailLogs(ctx context.Context, dc ContainerAPIClient, c ContainerInfo, n int) (string, error) {
+	logs, err := dc.ContainerLogs(ctx, c.ID, container.LogsOptions{
+		ShowStdout: true, ShowStderr: true, Tail: strconv.Itoa(n),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer logs.Close() // nolint:errcheck
+
+	var b bytes.Buffer
+	if _, err := stdcopy.StdCopy(&b, &b, logs); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// containerExitError gets the ContainerExitError for a container that has exited
+func containerExitError(ctx context.Context, lgr Logger, dc ContainerAPIClient, c ContainerInfo,
+	exitCode int) *ContainerExitError {
+	exitErr := &ContainerExitError{Container: c, ExitCode: exitCode}
+	if inspectResp, err := dc.ContainerInspect(ctx, c.ID); err != nil {
+		lgr.Log("Error inspecting exited container:", c.String(), "error:", err)
+	} else if inspectResp.ContainerJSONBase != nil && inspectResp.State != nil {
+		exitErr.OOMKilled = inspectResp.State.OOMKilled
+	}
+	if logs, err := tailLogs(ctx, dc, c, exitLogLines); err != nil {
+		lgr.Log("Error fetching exited container logs:", c.String(), "error:", err)
+	} else {
+		exitErr.Logs = logs
+	}
+	return exitErr
+}
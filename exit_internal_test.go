@@ -0,0 +1,44 @@
+package dktest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerExitError(t *testing.T) {
+	var logs bytes.Buffer
+	if _, err := stdcopy.NewStdWriter(&logs, stdcopy.Stderr).Write([]byte("invalid config\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		client   mockdockerclient.ContainerAPIClient
+		expected *ContainerExitError
+	}{
+		{name: "inspect and log fetch errors", client: mockdockerclient.ContainerAPIClient{},
+			expected: &ContainerExitError{ExitCode: 1}},
+		{name: "success", client: mockdockerclient.ContainerAPIClient{
+			InspectResp: &container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{
+				State: &container.State{OOMKilled: true},
+			}},
+			Logs: io.NopCloser(bytes.NewReader(logs.Bytes())),
+		}, expected: &ContainerExitError{ExitCode: 1, OOMKilled: true, Logs: "invalid config\n"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			exitErr := containerExitError(context.Background(), t, &client, containerInfo, 1)
+			assert.Equal(t, tc.expected, exitErr)
+			assert.NotEmpty(t, exitErr.Error())
+		})
+	}
+}
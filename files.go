@@ -0,0 +1,257 @@
+package dktest
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+const (
+	defaultFileMode fs.FileMode = 0o644
+)
+
+// File is a file or directory that's copied into the container before the container is started.
+// Exactly one of Content, HostPath, or FS must be specified.
+type File struct {
+	// ContainerPath is the absolute path of the file or directory in the container.
+	// Missing parent directories are created.
+	ContainerPath string
+	// Content is the content of the file
+	Content []byte
+	// HostPath is the path of the file or directory on the host. Directories are copied recursively.
+	HostPath string
+	// FS is the file system that's recursively copied into the ContainerPath directory
+	FS fs.FS
+	// Mode is the permissions of the file when the Content is specified. Defaults to 0644
+	Mode fs.FileMode
+}
+
+func (f File) validate() error {
+	if !path.IsAbs(f.ContainerPath) {
+		return fmt.Errorf("%w: %q", errFileNotAbs, f.ContainerPath)
+	}
+	sources := 0
+	if f.Content != nil {
+		sources++
+	}
+	if f.HostPath != "" {
+		sources++
+	}
+	if f.FS != nil {
+		sources++
+	}
+	if sources != 1 {
+		return fmt.Errorf("%w: %q", errFileSource, f.ContainerPath)
+	}
+	return nil
+}
+
+// writeTar writes the file to the tar archive. The tar entries are relative to the container's root directory.
+func (f File) writeTar(tw *tar.Writer) error {
+	name := strings.TrimPrefix(path.Clean(f.ContainerPath), "/")
+	switch {
+	case f.Content != nil:
+		mode := f.Mode
+		if mode == 0 {
+			mode = defaultFileMode
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(mode.Perm()),
+			Size:     int64(len(f.Content)),
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(f.Content)
+		return err
+	case f.FS != nil:
+		return writeFSTar(tw, f.FS, name)
+	default:
+		info, err := os.Stat(f.HostPath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return writeFSTar(tw, os.DirFS(f.HostPath), name)
+		}
+		return writeFileTar(tw, os.DirFS(filepath.Dir(f.HostPath)), filepath.Base(f.HostPath), name)
+	}
+}
+
+// writeFSTar writes all of the files and directories in the file system to the tar archive under the given directory
+func writeFSTar(tw *tar.Writer, fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, ".", func(p string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return writeFileTar(tw, fsys, p, path.Join(dir, p))
+	})
+}
+
+// writeFileTar writes the file or directory in the file system to the tar archive with the given name
+func writeFileTar(tw *tar.Writer, fsys fs.FS, p, name string) error {
+	info, err := fs.Stat(fsys, p)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return fmt.Errorf("%w: %q", errFileType, p)
+	}
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	// Files are owned by the container's root user
+	hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+
+	file, err := fsys.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close() // nolint:errcheck
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// filesTar creates a tar archive of the files to be extracted into the container's root directory
+func filesTar(files []File) (io.Reader, error) {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for _, f := range files {
+		if err := f.validate(); err != nil {
+			return nil, err
+		}
+		if err := f.writeTar(tw); err != nil {
+			return nil, fmt.Errorf("error archiving file: %q error: %w", f.ContainerPath, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func copyFiles(ctx context.Context, lgr Logger, dc ContainerAPIClient, c ContainerInfo, files []File) error {
+	archive, err := filesTar(files)
+	if err != nil {
+		return err
+	}
+	if err := dc.CopyToContainer(ctx, c.ID, "/", archive, container.CopyToContainerOptions{}); err != nil {
+		return err
+	}
+	lgr.Log("Copied files to container:", c.String())
+	return nil
+}
+
+// CopyFrom copies the file or directory at the given path in the container into the host directory.
+// e.g. copying "/var/log/app" from the container into "/tmp/out" creates "/tmp/out/app"
+// Only regular files and directories are copied. Other file types, such as symlinks, are skipped.
+// Copying fails if the path of a copied file in the host directory already contains a symlink.
+func (c ContainerInfo) CopyFrom(ctx context.Context, containerPath, hostDir string) error {
+	if c.client == nil {
+		return errNoClient
+	}
+	rc, _, err := c.client.CopyFromContainer(ctx, c.ID, containerPath)
+	if err != nil {
+		return err
+	}
+	defer rc.Close() // nolint:errcheck
+	return extractTar(rc, hostDir)
+}
+
+// extractTar extracts the regular files and directories in the tar archive into the directory
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if rel, err := filepath.Rel(dir, target); err != nil || rel == ".." ||
+			strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%w: %q", errFileOutsideDir, hdr.Name)
+		}
+		if hdr.Typeflag == tar.TypeDir || hdr.Typeflag == tar.TypeReg {
+			if err := checkNoSymlinks(dir, target); err != nil {
+				return err
+			}
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, hdr.FileInfo().Mode().Perm()|0o700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, target, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkNoSymlinks checks that none of the existing components of the target's path within the directory are symlinks,
+// so that symlinks that already exist in the directory can't redirect the extraction outside of the directory.
+// e.g. "out" -> "/etc"
+func checkNoSymlinks(dir, target string) error {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return err
+	}
+	p := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			// The rest of the path is created by the extraction
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %q is a symlink", errFileOutsideDir, p)
+		}
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, target string, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil { // nolint:gosec
+		f.Close() // nolint:errcheck,gosec
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,219 @@
+package dktest
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// readTar reads the tar archive into a map of entry names to contents
+func readTar(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+	entries := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[hdr.Name] = string(b)
+	}
+}
+
+func writeTar(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for name, content := range entries {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(content))}
+		if content == "" {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0o755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestFileValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		file        File
+		expectedErr error
+	}{
+		{name: "relative path", file: File{ContainerPath: "seed.sql", Content: []byte{}},
+			expectedErr: errFileNotAbs},
+		{name: "no source", file: File{ContainerPath: "/seed.sql"}, expectedErr: errFileSource},
+		{name: "multiple sources", file: File{ContainerPath: "/seed.sql", Content: []byte{}, HostPath: "seed.sql"},
+			expectedErr: errFileSource},
+		{name: "content", file: File{ContainerPath: "/seed.sql", Content: []byte{}}, expectedErr: nil},
+		{name: "host path", file: File{ContainerPath: "/seed.sql", HostPath: "seed.sql"}, expectedErr: nil},
+		{name: "fs", file: File{ContainerPath: "/seeds", FS: fstest.MapFS{}}, expectedErr: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.file.validate(); !errors.Is(err, tc.expectedErr) {
+				t.Error("Got unexpected error:", err, "!=", tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestFilesTar(t *testing.T) {
+	hostDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(hostDir, "certs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(hostDir, "certs", "ca.pem"), []byte("ca"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hostFile := filepath.Join(hostDir, "app.conf")
+	if err := os.WriteFile(hostFile, []byte("conf"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		files     []File
+		expected  map[string]string
+		expectErr bool
+	}{
+		{name: "no files", files: nil, expected: map[string]string{}},
+		{name: "content", files: []File{{ContainerPath: "/docker-entrypoint-initdb.d/seed.sql",
+			Content: []byte("SELECT 1;")}},
+			expected: map[string]string{"docker-entrypoint-initdb.d/seed.sql": "SELECT 1;"}},
+		{name: "fs", files: []File{{ContainerPath: "/seeds", FS: fstest.MapFS{
+			"a.sql":     {Data: []byte("a")},
+			"sub/b.sql": {Data: []byte("b")},
+		}}}, expected: map[string]string{
+			"seeds/": "", "seeds/a.sql": "a", "seeds/sub/": "", "seeds/sub/b.sql": "b",
+		}},
+		{name: "host file", files: []File{{ContainerPath: "/etc/app/app.conf", HostPath: hostFile}},
+			expected: map[string]string{"etc/app/app.conf": "conf"}},
+		{name: "host dir", files: []File{{ContainerPath: "/etc/app/", HostPath: filepath.Join(hostDir, "certs")}},
+			expected: map[string]string{"etc/app/": "", "etc/app/ca.pem": "ca"}},
+		{name: "missing host path", files: []File{{ContainerPath: "/etc/app",
+			HostPath: filepath.Join(hostDir, "missing")}}, expectErr: true},
+		{name: "invalid file", files: []File{{ContainerPath: "/etc/app"}}, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := filesTar(tc.files)
+			testErr(t, err, tc.expectErr)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.expected, readTar(t, r))
+		})
+	}
+}
+
+func TestCopyFiles(t *testing.T) {
+	files := []File{{ContainerPath: "/seed.sql", Content: []byte("SELECT 1;")}}
+
+	testCases := []struct {
+		name      string
+		client    mockdockerclient.ContainerAPIClient
+		files     []File
+		expectErr bool
+	}{
+		{name: "success", client: mockdockerclient.ContainerAPIClient{}, files: files, expectErr: false},
+		{name: "copy error", client: mockdockerclient.ContainerAPIClient{CopyToErr: mockdockerclient.Err},
+			files: files, expectErr: true},
+		{name: "invalid file", client: mockdockerclient.ContainerAPIClient{}, files: []File{{}}, expectErr: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			err := copyFiles(ctx, t, &client, containerInfo, tc.files)
+			testErr(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestContainerInfoCopyFrom(t *testing.T) {
+	outsideDir := t.TempDir()
+	reportTar := func() io.ReadCloser {
+		return io.NopCloser(bytes.NewReader(writeTar(t, map[string]string{"out/": "", "out/report.xml": "report"})))
+	}
+
+	testCases := []struct {
+		name   string
+		client ContainerAPIClient
+		// symlinks are created in the host directory before copying
+		symlinks  map[string]string
+		expected  map[string]string
+		expectErr bool
+	}{
+		{name: "no client", client: nil, expectErr: true},
+		{name: "copy error", client: &mockdockerclient.ContainerAPIClient{}, expectErr: true},
+		{name: "success", client: &mockdockerclient.ContainerAPIClient{
+			CopyFromResp: io.NopCloser(bytes.NewReader(writeTar(t, map[string]string{
+				"out/": "", "out/report.xml": "report", "out/sub/": "", "out/sub/cover.out": "cover",
+			}))),
+		}, expected: map[string]string{"out/report.xml": "report", "out/sub/cover.out": "cover"}, expectErr: false},
+		{name: "outside of dir", client: &mockdockerclient.ContainerAPIClient{
+			CopyFromResp: io.NopCloser(bytes.NewReader(writeTar(t, map[string]string{"../escaped": "escaped"}))),
+		}, expectErr: true},
+		{name: "symlinked dir", client: &mockdockerclient.ContainerAPIClient{CopyFromResp: reportTar()},
+			symlinks: map[string]string{"out": outsideDir}, expectErr: true},
+		{name: "symlinked file", client: &mockdockerclient.ContainerAPIClient{CopyFromResp: reportTar()},
+			symlinks:  map[string]string{"out/report.xml": filepath.Join(outsideDir, "report.xml")},
+			expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, target := range tc.symlinks {
+				p := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(target, p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := ContainerInfo{client: tc.client}
+			err := c.CopyFrom(context.Background(), "/out", dir)
+			testErr(t, err, tc.expectErr)
+			if _, err := os.Stat(filepath.Join(outsideDir, "report.xml")); err == nil {
+				t.Error("Expected no file to be written outside of the directory")
+			}
+			for name, expectedContent := range tc.expected {
+				b, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, expectedContent, string(b))
+			}
+		})
+	}
+}
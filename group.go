@@ -0,0 +1,270 @@
+package dktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ContainerSpec specifies a Docker image to run as part of a group of containers
+type ContainerSpec struct {
+	// Name identifies the container within the group and is used as the key for the container's ContainerInfo.
+	// Names must be unique within a group.
+	Name      string
+	ImageName string
+	Options   Options
+}
+
+func validateSpecs(specs []ContainerSpec) error {
+	if len(specs) == 0 {
+		return errNoSpecs
+	}
+	names := make(map[string]struct{}, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" {
+			return errNoSpecName
+		}
+		if _, ok := names[spec.Name]; ok {
+			return fmt.Errorf("duplicate container spec name: %q", spec.Name)
+		}
+		names[spec.Name] = struct{}{}
+		// The group's containers are run using a single Docker client, which is created using the first spec's Options
+		if !sameClient(spec.Options.Client, specs[0].Options.Client) ||
+			spec.Options.DockerAPIVersion != specs[0].Options.DockerAPIVersion {
+			return fmt.Errorf("%w: %q", errGroupClient, spec.Name)
+		}
+	}
+	return nil
+}
+
+// sameClient checks if the clients are the same. Clients that aren't comparable are never the same.
+func sameClient(a, b Client) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return reflect.TypeOf(a).Comparable() && a == b
+}
+
+// initSpecs copies the specs and initializes their Options so the caller's specs aren't modified.
+// Containers attached to the group's network are aliased by their spec's Name.
+func initSpecs(specs []ContainerSpec) []ContainerSpec {
+	specs = append([]ContainerSpec(nil), specs...)
+	for i := range specs {
+		specs[i].Options.init()
+		if network := specs[i].Options.Network; network != nil {
+			aliases := append([]string{specs[i].Name}, network.Aliases...)
+			specs[i].Options.Network = &NetworkOptions{Aliases: aliases}
+		}
+	}
+	return specs
+}
+
+// groupNetworkTimeout gets the longest Timeout of the specs that use a network.
+// The group only needs a network if at least one of the specs uses a network.
+func groupNetworkTimeout(specs []ContainerSpec) (time.Duration, bool) {
+	var timeout time.Duration
+	needsNetwork := false
+	for _, spec := range specs {
+		if spec.Options.Network == nil {
+			continue
+		}
+		needsNetwork = true
+		timeout = max(timeout, spec.Options.Timeout)
+	}
+	return timeout, needsNetwork
+}
+
+// groupCleanupTimeout gets the longest CleanupTimeout of the specs
+func groupCleanupTimeout(specs []ContainerSpec) time.Duration {
+	var timeout time.Duration
+	for _, spec := range specs {
+		timeout = max(timeout, spec.Options.CleanupTimeout)
+	}
+	return timeout
+}
+
+// groupKeepOnFailure checks if any of the group's containers, and so the group's network, may be kept on failure
+func groupKeepOnFailure(specs []ContainerSpec) bool {
+	for _, spec := range specs {
+		if keepOnFailure(spec.Options) {
+			return true
+		}
+	}
+	return false
+}
+
+// startGroup concurrently starts a container for each spec. The first failure cancels the startup of the remaining
+// containers. The returned ContainerInfos are in the same order as the specs and every ContainerInfo with an ID
+// needs to be stopped by the caller, even if an error is returned.
+func startGroup(ctx context.Context, lgr Logger, dc Client, specs []ContainerSpec,
+	netName string) ([]ContainerInfo, error) {
+	startCtx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+
+	containers := make([]ContainerInfo, len(specs))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for i, spec := range specs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			containers[i], err = startContainer(startCtx, lgr, dc, spec.ImageName, netName, spec.Options)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("error starting container: %q error: %w", spec.Name, err)
+					cancelFunc()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return containers, firstErr
+}
+
+// stopGroup concurrently stops the started containers. Images are only removed once all of the containers have been
+// stopped since multiple containers in the group may use the same image.
+// If the group failed, containers that should be kept on failure aren't stopped and true is returned.
+func stopGroup(ctx context.Context, lgr Logger, dc Client, specs []ContainerSpec, containers []ContainerInfo,
+	netName string, failed bool) (kept bool) {
+	keptContainers := make([]bool, len(containers))
+	var wg sync.WaitGroup
+	for i, c := range containers {
+		if c.reused {
+			c.logStream.stop()
+			lgr.Log("Keeping reusable container:", c.String())
+			continue
+		}
+		if c.ID == "" {
+			continue
+		}
+		if failed && keepOnFailure(specs[i].Options) {
+			c.logStream.stop()
+			keepContainer(lgr, c, netName, specs[i].Options.CleanupImage)
+			reaperKeep(lgr, c.ID)
+			live.remove(c.ID)
+			keptContainers[i], kept = true, true
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts := specs[i].Options
+			stopCtx, stopTimeoutCancelFunc := context.WithTimeout(ctx, opts.CleanupTimeout)
+			defer stopTimeoutCancelFunc()
+			stopContainer(stopCtx, lgr, dc, c, opts.LogStdout, opts.LogStderr)
+		}()
+	}
+	wg.Wait()
+
+	removed := make(map[string]struct{})
+	for i, spec := range specs {
+		imgName := containers[i].ImageName
+		if !spec.Options.CleanupImage || imgName == "" || containers[i].reused || keptContainers[i] {
+			continue
+		}
+		if _, ok := removed[imgName]; ok {
+			continue
+		}
+		removed[imgName] = struct{}{}
+		func() {
+			removeCtx, removeTimeoutCancelFunc := context.WithTimeout(ctx, spec.Options.CleanupTimeout)
+			defer removeTimeoutCancelFunc()
+			removeImage(removeCtx, lgr, dc, imgName)
+		}()
+	}
+
+	return kept
+}
+
+// RunGroup runs the given test function once all of the specified Docker images are running in containers.
+// The containers are pulled, started, and checked for readiness concurrently. The test function is given each
+// container's ContainerInfo keyed by the ContainerSpec's Name.
+// If any container fails to start, all of the containers are stopped and removed.
+func RunGroup(t *testing.T, specs []ContainerSpec, testFunc func(*testing.T, map[string]ContainerInfo)) {
+	err := RunGroupContext(context.Background(), t, specs, func(containers map[string]ContainerInfo) error {
+		return runTestFunc(t, func() { testFunc(t, containers) })
+	})
+	if err != nil && !errors.Is(err, errTestFailed) {
+		t.Fatal("Failed:", err)
+	}
+}
+
+// RunGroupContext is similar to RunGroup, but takes a parent context and returns an error and doesn't rely on a
+// testing.T.
+// All of the containers are run using the same Docker client, so every ContainerSpec's Options must specify the same
+// Client and DockerAPIVersion.
+func RunGroupContext(ctx context.Context, logger Logger, specs []ContainerSpec,
+	testFunc func(map[string]ContainerInfo) error) (retErr error) {
+	if err := validateSpecs(specs); err != nil {
+		return err
+	}
+
+	dc, closeClient, err := getClient(ctx, logger, specs[0].Options)
+	if err != nil {
+		return fmt.Errorf("error getting Docker client: %w", err)
+	}
+	defer func() {
+		if err := closeClient(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("error closing Docker client: %w", err)
+		}
+	}()
+
+	startReaper(logger, dc, specs[0].Options)
+
+	specs = initSpecs(specs)
+
+	var (
+		netName string
+		kept    bool
+	)
+	if timeout, ok := groupNetworkTimeout(specs); ok {
+		netCtx, netTimeoutCancelFunc := context.WithTimeout(ctx, timeout)
+		netName, err = createNetwork(netCtx, logger, dc, groupCleanupTimeout(specs), groupKeepOnFailure(specs))
+		netTimeoutCancelFunc()
+		if err != nil {
+			return fmt.Errorf("error creating network: %w", err)
+		}
+		defer func() {
+			if kept {
+				logger.Log("Keeping network after failure:", netName)
+				reaperKeep(logger, netName)
+				live.remove(netName)
+				return
+			}
+			removeCtx, removeTimeoutCancelFunc := context.WithTimeout(ctx, groupCleanupTimeout(specs))
+			defer removeTimeoutCancelFunc()
+			removeNetwork(removeCtx, logger, dc, netName)
+		}()
+	}
+
+	// failed is only cleared once the test func returns successfully, so a test func that panics or calls
+	// t.FailNow() is also a failure
+	failed := true
+	containers, err := startGroup(ctx, logger, dc, specs, netName)
+	defer func() {
+		kept = stopGroup(ctx, logger, dc, specs, containers, netName, failed)
+	}()
+	if err != nil {
+		return err
+	}
+
+	containerInfos := make(map[string]ContainerInfo, len(specs))
+	for i, spec := range specs {
+		containerInfos[spec.Name] = containers[i]
+	}
+	if err := testFunc(containerInfos); err != nil {
+		return fmt.Errorf("error running test func: %w", err)
+	}
+	failed = false
+
+	return nil
+}
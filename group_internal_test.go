@@ -0,0 +1,129 @@
+package dktest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSpecs(t *testing.T) {
+	client := &mockdockerclient.Client{}
+
+	testCases := []struct {
+		name        string
+		specs       []ContainerSpec
+		expectedErr error
+		expectErr   bool
+	}{
+		{name: "nil", specs: nil, expectedErr: errNoSpecs, expectErr: true},
+		{name: "no name", specs: []ContainerSpec{{ImageName: imageName}}, expectedErr: errNoSpecName,
+			expectErr: true},
+		{name: "duplicate name", specs: []ContainerSpec{{Name: "a"}, {Name: "a"}}, expectErr: true},
+		{name: "success", specs: []ContainerSpec{{Name: "a"}, {Name: "b"}}, expectErr: false},
+		{name: "same client", specs: []ContainerSpec{{Name: "a", Options: Options{Client: client}},
+			{Name: "b", Options: Options{Client: client}}}, expectErr: false},
+		{name: "different clients", specs: []ContainerSpec{{Name: "a", Options: Options{Client: client}},
+			{Name: "b", Options: Options{Client: &mockdockerclient.Client{}}}}, expectedErr: errGroupClient,
+			expectErr: true},
+		{name: "client only on first spec", specs: []ContainerSpec{{Name: "a", Options: Options{Client: client}},
+			{Name: "b"}}, expectedErr: errGroupClient, expectErr: true},
+		{name: "different API versions", specs: []ContainerSpec{{Name: "a"},
+			{Name: "b", Options: Options{DockerAPIVersion: "1.44"}}}, expectedErr: errGroupClient, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSpecs(tc.specs)
+			testErr(t, err, tc.expectErr)
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Error("Got unexpected error:", err, "!=", tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestInitSpecs(t *testing.T) {
+	specs := []ContainerSpec{
+		{Name: "a"},
+		{Name: "b", Options: Options{Network: &NetworkOptions{}}},
+		{Name: "c", Options: Options{Network: &NetworkOptions{Aliases: []string{"foo"}}}},
+	}
+	initialized := initSpecs(specs)
+
+	assert.Nil(t, initialized[0].Options.Network)
+	assert.Equal(t, []string{"b"}, initialized[1].Options.Network.Aliases)
+	assert.Equal(t, []string{"c", "foo"}, initialized[2].Options.Network.Aliases)
+	// the spec's name is the first alias the container is attached to the network with
+	assert.Equal(t, &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+		"net": {Aliases: []string{"c", "foo"}},
+	}}, networkingConfig("net", initialized[2].Options))
+	assert.Equal(t, DefaultTimeout, initialized[0].Options.Timeout)
+	// the caller's specs should not be modified
+	assert.Equal(t, []string{"foo"}, specs[2].Options.Network.Aliases)
+	assert.Equal(t, time.Duration(0), specs[0].Options.Timeout)
+
+	timeout, ok := groupNetworkTimeout(initialized)
+	assert.True(t, ok)
+	assert.Equal(t, DefaultTimeout, timeout)
+	_, ok = groupNetworkTimeout(initialized[:1])
+	assert.False(t, ok)
+}
+
+func TestStartGroup(t *testing.T) {
+	successPullResp := mockdockerclient.MockReadCloser{MockReader: mockdockerclient.MockReader{Err: io.EOF}}
+	successCreateResp := &container.CreateResponse{ID: "containerID"}
+
+	testCases := []struct {
+		name            string
+		client          mockdockerclient.Client
+		readyFunc       func(context.Context, ContainerInfo) bool
+		expectErr       bool
+		expectContainer bool
+	}{
+		{name: "success", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+			ImageAPIClient:     mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}, readyFunc: alwaysReady, expectErr: false, expectContainer: true},
+		{name: "pull error", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+		}, readyFunc: alwaysReady, expectErr: true, expectContainer: false},
+		{name: "create error", client: mockdockerclient.Client{
+			ImageAPIClient: mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}, readyFunc: alwaysReady, expectErr: true, expectContainer: false},
+		{name: "ready func panics", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp},
+			ImageAPIClient:     mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}, readyFunc: func(context.Context, ContainerInfo) bool { panic("not ready") }, expectErr: true,
+			expectContainer: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			specs := []ContainerSpec{{Name: "a", ImageName: imageName}, {Name: "b", ImageName: imageName}}
+			for i := range specs {
+				specs[i].Options.ReadyFunc = tc.readyFunc
+				specs[i].Options.init()
+			}
+			containers, err := startGroup(ctx, t, &client, specs, "")
+			testErr(t, err, tc.expectErr)
+			if len(containers) != len(specs) {
+				t.Fatal("Got wrong number of containers:", len(containers), "!=", len(specs))
+			}
+			for _, c := range containers {
+				if hasContainer := c.ID != ""; hasContainer != tc.expectContainer {
+					t.Error("Expected container:", tc.expectContainer, "got container:", c.String())
+				}
+			}
+			stopGroup(ctx, t, &client, specs, containers, "", false)
+		})
+	}
+}
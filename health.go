@@ -0,0 +1,41 @@
+package dktest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// containerHealthy checks if the container's healthcheck reports that the container is healthy.
+// An error is returned if the container will never become healthy. e.g. the container is unhealthy, has stopped
+// running, or has no healthcheck
+func containerHealthy(ctx context.Context, lgr Logger, dc ContainerAPIClient, c ContainerInfo) (bool, error) {
+	inspectResp, err := dc.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		// The inspect may have timed out, so try again on the next check
+		return false, nil
+	}
+	if inspectResp.ContainerJSONBase == nil || inspectResp.State == nil {
+		return false, nil
+	}
+	state := inspectResp.State
+	if !state.Running {
+		return false, containerExitError(ctx, lgr, dc, c, state.ExitCode)
+	}
+	if state.Health == nil || state.Health.Status == container.NoHealthcheck {
+		return false, errNoHealthcheck
+	}
+
+	switch state.Health.Status {
+	case container.Healthy:
+		return true, nil
+	case container.Unhealthy:
+		if n := len(state.Health.Log); n > 0 && state.Health.Log[n-1] != nil {
+			return false, fmt.Errorf("%w: %s", errContainerUnhealthy, state.Health.Log[n-1].Output)
+		}
+		return false, errContainerUnhealthy
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,51 @@
+package dktest
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keepOnFailureEnvVar keeps containers after failed tests when set to a true value
+const keepOnFailureEnvVar = "DKTEST_KEEP_ON_FAILURE"
+
+// keepOnFailure checks if the container should be kept when the test fails
+func keepOnFailure(opts Options) bool {
+	if opts.KeepOnFailure {
+		return true
+	}
+	enabled, err := strconv.ParseBool(os.Getenv(keepOnFailureEnvVar))
+	return err == nil && enabled
+}
+
+// formatPorts formats the container's mapped ports. e.g. "80/tcp -> 127.0.0.1:32768"
+func formatPorts(c ContainerInfo) string {
+	ports := make([]string, 0, len(c.Ports))
+	for port, bindings := range c.Ports {
+		for _, pb := range bindings {
+			ports = append(ports, fmt.Sprintf("%v -> %v:%v", port, mapHost(pb.HostIP), pb.HostPort))
+		}
+	}
+	sort.Strings(ports)
+	return strings.Join(ports, ", ")
+}
+
+// keepContainer logs the kept container along with the commands used to debug and clean up the container
+func keepContainer(lgr Logger, c ContainerInfo, netName string, cleanupImage bool) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ID: %v\n", c.ID)
+	fmt.Fprintf(&b, "Name: %v\n", c.Name)
+	fmt.Fprintf(&b, "Ports: %v\n", formatPorts(c))
+	fmt.Fprintf(&b, "Inspect: docker exec -it %v sh\n", c.Name)
+	fmt.Fprintf(&b, "Logs: docker logs %v\n", c.Name)
+	fmt.Fprintf(&b, "Clean up: docker rm -f %v", c.Name)
+	if netName != "" {
+		fmt.Fprintf(&b, " && docker network rm %v", netName)
+	}
+	if cleanupImage && c.ImageName != "" {
+		fmt.Fprintf(&b, " && docker image rm %v", c.ImageName)
+	}
+	lgr.Log("Keeping container after failure:", c.String(), "\n"+b.String())
+}
@@ -0,0 +1,158 @@
+package dktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepOnFailure(t *testing.T) {
+	testCases := []struct {
+		name          string
+		keepOnFailure bool
+		envVar        string
+		expected      bool
+	}{
+		{name: "not kept", keepOnFailure: false, expected: false},
+		{name: "not kept - env var false", keepOnFailure: false, envVar: "false", expected: false},
+		{name: "not kept - env var invalid", keepOnFailure: false, envVar: "invalid", expected: false},
+		{name: "kept", keepOnFailure: true, expected: true},
+		{name: "kept - env var true", keepOnFailure: false, envVar: "true", expected: true},
+		{name: "kept - env var 1", keepOnFailure: false, envVar: "1", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(keepOnFailureEnvVar, tc.envVar)
+			assert.Equal(t, tc.expected, keepOnFailure(Options{KeepOnFailure: tc.keepOnFailure}))
+		})
+	}
+}
+
+func TestFormatPorts(t *testing.T) {
+	_, portBindings, err := nat.ParsePortSpecs([]string{"8181:80", "10.0.0.1:5433:5432"})
+	if err != nil {
+		t.Fatal("Error parsing port bindings:", err)
+	}
+	assert.Equal(t, "5432/tcp -> 10.0.0.1:5433, 80/tcp -> 127.0.0.1:8181",
+		formatPorts(ContainerInfo{Ports: portBindings}))
+	assert.Empty(t, formatPorts(ContainerInfo{}))
+}
+
+func TestKeepContainer(t *testing.T) {
+	c := ContainerInfo{ID: "containerID", Name: "dktest_name", ImageName: imageName}
+
+	testCases := []struct {
+		name         string
+		netName      string
+		cleanupImage bool
+		expected     string
+	}{
+		{name: "container", expected: "Clean up: docker rm -f dktest_name"},
+		{name: "with network", netName: "dktest_network",
+			expected: "Clean up: docker rm -f dktest_name && docker network rm dktest_network"},
+		{name: "with image", cleanupImage: true,
+			expected: "Clean up: docker rm -f dktest_name && docker image rm " + imageName},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lgr := &recordingLogger{}
+			keepContainer(lgr, c, tc.netName, tc.cleanupImage)
+			if !assert.Len(t, lgr.logs, 1) {
+				return
+			}
+			msg := fmt.Sprint(lgr.logs[0]...)
+			assert.Contains(t, msg, "ID: containerID")
+			assert.Contains(t, msg, "docker exec -it dktest_name sh")
+			assert.Contains(t, msg, "docker logs dktest_name")
+			assert.Contains(t, msg, tc.expected)
+		})
+	}
+}
+
+func TestRunContextKeepOnFailure(t *testing.T) {
+	successPullResp := mockdockerclient.MockReadCloser{MockReader: mockdockerclient.MockReader{Err: io.EOF}}
+	errForTest := errors.New("testFunc failed")
+
+	testCases := []struct {
+		name          string
+		keepOnFailure bool
+		readyFunc     func(context.Context, ContainerInfo) bool
+		testFuncErr   error
+		kept          bool
+	}{
+		{name: "success", keepOnFailure: true, readyFunc: alwaysReady, kept: false},
+		{name: "test func error", keepOnFailure: true, readyFunc: alwaysReady, testFuncErr: errForTest, kept: true},
+		{name: "not ready", keepOnFailure: true, readyFunc: neverReady, kept: true},
+		{name: "test func error - not kept", keepOnFailure: false, readyFunc: alwaysReady, testFuncErr: errForTest,
+			kept: false},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockdockerclient.Client{
+				ContainerAPIClient: mockdockerclient.ContainerAPIClient{
+					CreateResp: &container.CreateResponse{ID: "containerID"}},
+				ImageAPIClient: mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+			}
+			lgr := &recordingLogger{}
+			_ = RunContext(ctx, lgr, imageName, Options{
+				Client:        client,
+				ReadyFunc:     tc.readyFunc,
+				Timeout:       100 * time.Millisecond,
+				KeepOnFailure: tc.keepOnFailure,
+			}, func(ContainerInfo) error {
+				return tc.testFuncErr
+			})
+			assert.Equal(t, tc.kept, lgr.logged("Keeping container after failure:"))
+			assert.Equal(t, !tc.kept, lgr.logged("Stopped container:"))
+		})
+	}
+
+	t.Run("test func panic", func(t *testing.T) {
+		client := &mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{
+				CreateResp: &container.CreateResponse{ID: "containerID"}},
+			ImageAPIClient: mockdockerclient.ImageAPIClient{PullResp: successPullResp},
+		}
+		lgr := &recordingLogger{}
+		func() {
+			defer func() { _ = recover() }()
+			_ = RunContext(ctx, lgr, imageName, Options{Client: client, KeepOnFailure: true},
+				func(ContainerInfo) error { panic("test func panic") })
+		}()
+		assert.True(t, lgr.logged("Keeping container after failure:"))
+		assert.False(t, lgr.logged("Stopped container:"))
+	})
+}
+
+func TestStopGroupKeepOnFailure(t *testing.T) {
+	specs := []ContainerSpec{
+		{Name: "kept", ImageName: imageName, Options: Options{KeepOnFailure: true, CleanupImage: true}},
+		{Name: "stopped", ImageName: imageName},
+	}
+	containers := []ContainerInfo{{ID: "keptID", ImageName: imageName}, {ID: "stoppedID", ImageName: imageName}}
+	client := &mockdockerclient.Client{}
+	ctx := context.Background()
+
+	lgr := &recordingLogger{}
+	assert.False(t, stopGroup(ctx, lgr, client, specs, containers, "", false))
+	assert.False(t, lgr.logged("Keeping container after failure:"))
+	assert.True(t, lgr.logged("Removing image:"))
+
+	lgr = &recordingLogger{}
+	assert.True(t, stopGroup(ctx, lgr, client, specs, containers, "", true))
+	assert.True(t, lgr.logged("Keeping container after failure:"))
+	assert.True(t, lgr.logged("Stopped container:"))
+	assert.False(t, lgr.logged("Removing image:"))
+}
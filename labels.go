@@ -0,0 +1,48 @@
+package dktest
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Labels on the resources created by dktest. The labels can be used to find the resources created by a test run.
+// e.g. docker ps -a --filter label=dktest.test=TestFoo
+const (
+	// Label is on every resource created by dktest
+	Label = "dktest"
+	// SessionLabel contains the ID of the session that created the resource. Each process is a session.
+	// Reusable containers outlive their session, so they don't have a SessionLabel.
+	SessionLabel = "dktest.session"
+	// TestLabel contains the name of the test that created the resource. e.g. t.Name()
+	TestLabel = "dktest.test"
+	// HostnameLabel contains the hostname of the host that created the resource
+	HostnameLabel = "dktest.hostname"
+	// PIDLabel contains the process ID of the process that created the resource
+	PIDLabel = "dktest.pid"
+	// CreatedLabel contains the time the resource was created in RFC 3339 format
+	CreatedLabel = "dktest.created"
+	// ReuseLabel contains the configuration hash of a reusable container
+	ReuseLabel = "dktest.reuse"
+	// KeepOnFailureLabel is on the containers and networks created with KeepOnFailure, which are kept after failures
+	KeepOnFailureLabel = "dktest.keep-on-failure"
+)
+
+// hostname is the hostname of the host running this process
+var hostname, _ = os.Hostname()
+
+// resourceLabels gets the labels for a resource created by the session. The test name is only known if the Logger is
+// a testing.TB.
+func resourceLabels(lgr Logger) map[string]string {
+	labels := map[string]string{
+		Label:         "true",
+		SessionLabel:  sessionID,
+		HostnameLabel: hostname,
+		PIDLabel:      strconv.Itoa(os.Getpid()),
+		CreatedLabel:  time.Now().UTC().Format(time.RFC3339),
+	}
+	if t, ok := lgr.(interface{ Name() string }); ok {
+		labels[TestLabel] = t.Name()
+	}
+	return labels
+}
@@ -0,0 +1,29 @@
+package dktest
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceLabels(t *testing.T) {
+	before := time.Now().Add(-time.Second)
+	labels := resourceLabels(t)
+	assert.Equal(t, "true", labels[Label])
+	assert.Equal(t, sessionID, labels[SessionLabel])
+	assert.Equal(t, t.Name(), labels[TestLabel])
+	assert.Equal(t, hostname, labels[HostnameLabel])
+	assert.Equal(t, strconv.Itoa(os.Getpid()), labels[PIDLabel])
+	created, err := time.Parse(time.RFC3339, labels[CreatedLabel])
+	if err != nil {
+		t.Fatal("Error parsing created label:", err)
+	}
+	assert.True(t, created.After(before), "created label is too old:", created)
+
+	// The test name is only known for a testing.TB
+	_, ok := resourceLabels(nopLogger{})[TestLabel]
+	assert.False(t, ok)
+}
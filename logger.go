@@ -4,3 +4,8 @@ package dktest
 type Logger interface {
 	Log(...interface{})
 }
+
+// nopLogger discards all logged messages
+type nopLogger struct{}
+
+func (nopLogger) Log(...interface{}) {}
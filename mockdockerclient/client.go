@@ -0,0 +1,11 @@
+package mockdockerclient
+
+// Client is a mock implementation of the Docker client APIs used by dktest.
+// e.g. the Docker client.ContainerAPIClient, client.ImageAPIClient, client.NetworkAPIClient, and
+// client.VolumeAPIClient interfaces
+type Client struct {
+	ContainerAPIClient
+	ImageAPIClient
+	NetworkAPIClient
+	VolumeAPIClient
+}
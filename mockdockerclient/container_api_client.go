@@ -3,6 +3,7 @@ package mockdockerclient
 import (
 	"context"
 	"io"
+	"net"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -15,12 +16,32 @@ import (
 
 // ContainerAPIClient is a mock implementation of the Docker's client.ContainerAPIClient interface
 type ContainerAPIClient struct {
-	CreateResp  *container.CreateResponse
+	CreateResp *container.CreateResponse
+	// CreateErr is returned by ContainerCreate() if specified
+	CreateErr   error
 	StartErr    error
 	StopErr     error
 	RemoveErr   error
 	InspectResp *container.InspectResponse
 	Logs        io.ReadCloser
+	// WaitResp is sent by ContainerWait() if specified
+	WaitResp *container.WaitResponse
+	// WaitErr is sent by ContainerWait() if specified
+	WaitErr error
+	// ExecCreateResp is returned by ContainerExecCreate() if specified. Otherwise, an error is returned.
+	ExecCreateResp *container.ExecCreateResponse
+	// ExecOutput is the multiplexed stdout and stderr output read from the connection returned by
+	// ContainerExecAttach() if specified. Otherwise, an error is returned.
+	ExecOutput []byte
+	// ExecInspectResp is returned by ContainerExecInspect() if specified. Otherwise, an error is returned.
+	ExecInspectResp *container.ExecInspect
+	CopyToErr       error
+	// CopyFromResp is returned by CopyFromContainer() if specified. Otherwise, an error is returned.
+	CopyFromResp io.ReadCloser
+	// ListResp is returned by ContainerList()
+	ListResp []container.Summary
+	// ListErr is returned by ContainerList() if specified
+	ListErr error
 }
 
 var _ client.ContainerAPIClient = (*ContainerAPIClient)(nil)
@@ -44,6 +65,9 @@ func (c *ContainerAPIClient) ContainerCommit(context.Context, string,
 // ContainerCreate is a mock implementation of Docker's client.ContainerAPIClient.ContainerCreate()
 func (c *ContainerAPIClient) ContainerCreate(context.Context, *container.Config, *container.HostConfig,
 	*network.NetworkingConfig, *v1.Platform, string) (container.CreateResponse, error) {
+	if c.CreateErr != nil {
+		return container.CreateResponse{}, c.CreateErr
+	}
 	if c.CreateResp == nil {
 		return container.CreateResponse{}, Err
 	}
@@ -59,27 +83,39 @@ func (c *ContainerAPIClient) ContainerDiff(context.Context,
 }
 
 // ContainerExecAttach is a mock implementation of Docker's client.ContainerAPIClient.ContainerExecAttach()
-//
-// TODO: properly implement
+// Anything written to the returned connection is discarded.
 func (c *ContainerAPIClient) ContainerExecAttach(context.Context, string,
 	container.ExecStartOptions) (types.HijackedResponse, error) {
-	return types.HijackedResponse{}, nil
+	if c.ExecOutput == nil {
+		return types.HijackedResponse{}, Err
+	}
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		_, _ = io.Copy(io.Discard, serverConn)
+	}()
+	go func() {
+		_, _ = serverConn.Write(c.ExecOutput)
+		_ = serverConn.Close()
+	}()
+	return types.NewHijackedResponse(clientConn, ""), nil
 }
 
 // ContainerExecCreate is a mock implementation of Docker's client.ContainerAPIClient.ContainerExecCreate()
-//
-// TODO: properly implement
 func (c *ContainerAPIClient) ContainerExecCreate(context.Context, string,
 	container.ExecOptions) (container.ExecCreateResponse, error) {
-	return container.ExecCreateResponse{}, nil
+	if c.ExecCreateResp == nil {
+		return container.ExecCreateResponse{}, Err
+	}
+	return *c.ExecCreateResp, nil
 }
 
 // ContainerExecInspect is a mock implementation of Docker's client.ContainerAPIClient.ContainerExecInspect()
-//
-// TODO: properly implement
 func (c *ContainerAPIClient) ContainerExecInspect(context.Context,
 	string) (container.ExecInspect, error) {
-	return container.ExecInspect{}, nil
+	if c.ExecInspectResp == nil {
+		return container.ExecInspect{}, Err
+	}
+	return *c.ExecInspectResp, nil
 }
 
 // ContainerExecResize is a mock implementation of Docker's client.ContainerAPIClient.ContainerExecResize()
@@ -129,11 +165,12 @@ func (c *ContainerAPIClient) ContainerKill(context.Context, string, string) erro
 }
 
 // ContainerList is a mock implementation of Docker's client.ContainerAPIClient.ContainerList()
-//
-// TODO: properly implement
 func (c *ContainerAPIClient) ContainerList(context.Context,
 	container.ListOptions) ([]container.Summary, error) {
-	return nil, nil
+	if c.ListErr != nil {
+		return nil, c.ListErr
+	}
+	return c.ListResp, nil
 }
 
 // ContainerLogs is a mock implementation of Docker's client.ContainerAPIClient.ContainerLogs()
@@ -235,27 +272,33 @@ func (c *ContainerAPIClient) ContainerUpdate(context.Context, string,
 }
 
 // ContainerWait is a mock implementation of Docker's client.ContainerAPIClient.ContainerWait()
-//
-// TODO: properly implement
+// If neither WaitResp nor WaitErr are specified, the container never stops running.
 func (c *ContainerAPIClient) ContainerWait(context.Context, string,
 	container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
-	return nil, nil
+	respCh := make(chan container.WaitResponse, 1)
+	errCh := make(chan error, 1)
+	if c.WaitResp != nil {
+		respCh <- *c.WaitResp
+	}
+	if c.WaitErr != nil {
+		errCh <- c.WaitErr
+	}
+	return respCh, errCh
 }
 
 // CopyFromContainer is a mock implementation of Docker's client.ContainerAPIClient.CopyFromContainer()
-//
-// TODO: properly implement
 func (c *ContainerAPIClient) CopyFromContainer(context.Context, string, string) (io.ReadCloser,
 	container.PathStat, error) {
-	return nil, container.PathStat{}, nil
+	if c.CopyFromResp == nil {
+		return nil, container.PathStat{}, Err
+	}
+	return c.CopyFromResp, container.PathStat{}, nil
 }
 
 // CopyToContainer is a mock implementation of Docker's client.ContainerAPIClient.CopyToContainer()
-//
-// TODO: properly implement
 func (c *ContainerAPIClient) CopyToContainer(context.Context, string, string, io.Reader,
 	container.CopyToContainerOptions) error {
-	return nil
+	return c.CopyToErr
 }
 
 // ContainersPrune is a mock implementation of Docker's client.ContainerAPIClient.ContainersPrune()
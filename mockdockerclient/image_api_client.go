@@ -15,15 +15,22 @@ var _ client.ImageAPIClient = (*ImageAPIClient)(nil)
 
 // ImageAPIClient is a mock implementation of the Docker's client.ImageAPIClient interface
 type ImageAPIClient struct {
-	PullResp io.ReadCloser
+	PullResp  io.ReadCloser
+	BuildResp io.ReadCloser
+	// InspectResp is returned by ImageInspect() if specified. Otherwise, an empty response is returned.
+	InspectResp *image.InspectResponse
+	// InspectErr is returned by ImageInspect() if specified
+	InspectErr error
+	LoadResp   io.ReadCloser
 }
 
 // ImageBuild is a mock implementation of Docker's client.ImageAPIClient.ImageBuild()
-//
-// TODO: properly implement
 func (c *ImageAPIClient) ImageBuild(context.Context, io.Reader,
 	build.ImageBuildOptions) (build.ImageBuildResponse, error) {
-	return build.ImageBuildResponse{}, nil
+	if c.BuildResp == nil {
+		return build.ImageBuildResponse{}, Err
+	}
+	return build.ImageBuildResponse{Body: c.BuildResp}, nil
 }
 
 // BuildCachePrune is a mock implementation of Docker's client.ImageAPIClient.BuildCachePrune()
@@ -77,10 +84,11 @@ func (c *ImageAPIClient) ImageList(context.Context, image.ListOptions) ([]image.
 }
 
 // ImageLoad is a mock implementation of Docker's client.ImageAPIClient.ImageLoad()
-//
-// TODO: properly implement
 func (c *ImageAPIClient) ImageLoad(context.Context, io.Reader, ...client.ImageLoadOption) (image.LoadResponse, error) {
-	return image.LoadResponse{}, nil
+	if c.LoadResp == nil {
+		return image.LoadResponse{}, Err
+	}
+	return image.LoadResponse{Body: c.LoadResp, JSON: true}, nil
 }
 
 // ImagePull is a mock implementation of Docker's client.ImageAPIClient.ImagePull()
@@ -134,8 +142,12 @@ func (c *ImageAPIClient) ImagesPrune(context.Context, filters.Args) (image.Prune
 }
 
 // ImageInspect is a mock implementation of Docker's client.ImageAPIClient.ImageInspect()
-//
-// TODO: properly implement
 func (c *ImageAPIClient) ImageInspect(context.Context, string, ...client.ImageInspectOption) (image.InspectResponse, error) {
-	return image.InspectResponse{}, nil
+	if c.InspectErr != nil {
+		return image.InspectResponse{}, c.InspectErr
+	}
+	if c.InspectResp == nil {
+		return image.InspectResponse{}, nil
+	}
+	return *c.InspectResp, nil
 }
@@ -0,0 +1,78 @@
+package mockdockerclient
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+var _ client.NetworkAPIClient = (*NetworkAPIClient)(nil)
+
+// NetworkAPIClient is a mock implementation of the Docker's client.NetworkAPIClient interface
+type NetworkAPIClient struct {
+	CreateResp *network.CreateResponse
+	RemoveErr  error
+	// ListResp is returned by NetworkList() if not nil. Otherwise, an error is returned.
+	ListResp []network.Summary
+}
+
+// NetworkConnect is a mock implementation of Docker's client.NetworkAPIClient.NetworkConnect()
+//
+// TODO: properly implement
+func (c *NetworkAPIClient) NetworkConnect(context.Context, string, string, *network.EndpointSettings) error {
+	return nil
+}
+
+// NetworkCreate is a mock implementation of Docker's client.NetworkAPIClient.NetworkCreate()
+func (c *NetworkAPIClient) NetworkCreate(context.Context, string,
+	network.CreateOptions) (network.CreateResponse, error) {
+	if c.CreateResp == nil {
+		return network.CreateResponse{}, Err
+	}
+	return *c.CreateResp, nil
+}
+
+// NetworkDisconnect is a mock implementation of Docker's client.NetworkAPIClient.NetworkDisconnect()
+//
+// TODO: properly implement
+func (c *NetworkAPIClient) NetworkDisconnect(context.Context, string, string, bool) error {
+	return nil
+}
+
+// NetworkInspect is a mock implementation of Docker's client.NetworkAPIClient.NetworkInspect()
+//
+// TODO: properly implement
+func (c *NetworkAPIClient) NetworkInspect(context.Context, string,
+	network.InspectOptions) (network.Inspect, error) {
+	return network.Inspect{}, nil
+}
+
+// NetworkInspectWithRaw is a mock implementation of Docker's client.NetworkAPIClient.NetworkInspectWithRaw()
+//
+// TODO: properly implement
+func (c *NetworkAPIClient) NetworkInspectWithRaw(context.Context, string,
+	network.InspectOptions) (network.Inspect, []byte, error) {
+	return network.Inspect{}, nil, nil
+}
+
+// NetworkList is a mock implementation of Docker's client.NetworkAPIClient.NetworkList()
+func (c *NetworkAPIClient) NetworkList(context.Context, network.ListOptions) ([]network.Summary, error) {
+	if c.ListResp == nil {
+		return nil, Err
+	}
+	return c.ListResp, nil
+}
+
+// NetworkRemove is a mock implementation of Docker's client.NetworkAPIClient.NetworkRemove()
+func (c *NetworkAPIClient) NetworkRemove(context.Context, string) error {
+	return c.RemoveErr
+}
+
+// NetworksPrune is a mock implementation of Docker's client.NetworkAPIClient.NetworksPrune()
+//
+// TODO: properly implement
+func (c *NetworkAPIClient) NetworksPrune(context.Context, filters.Args) (network.PruneReport, error) {
+	return network.PruneReport{}, nil
+}
@@ -0,0 +1,67 @@
+package mockdockerclient
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+var _ client.VolumeAPIClient = (*VolumeAPIClient)(nil)
+
+// VolumeAPIClient is a mock implementation of the Docker's client.VolumeAPIClient interface
+type VolumeAPIClient struct {
+	// ListResp is returned by VolumeList() if specified. Otherwise, an error is returned.
+	ListResp  *volume.ListResponse
+	RemoveErr error
+}
+
+// VolumeCreate is a mock implementation of Docker's client.VolumeAPIClient.VolumeCreate()
+//
+// TODO: properly implement
+func (c *VolumeAPIClient) VolumeCreate(context.Context, volume.CreateOptions) (volume.Volume, error) {
+	return volume.Volume{}, nil
+}
+
+// VolumeInspect is a mock implementation of Docker's client.VolumeAPIClient.VolumeInspect()
+//
+// TODO: properly implement
+func (c *VolumeAPIClient) VolumeInspect(context.Context, string) (volume.Volume, error) {
+	return volume.Volume{}, nil
+}
+
+// VolumeInspectWithRaw is a mock implementation of Docker's client.VolumeAPIClient.VolumeInspectWithRaw()
+//
+// TODO: properly implement
+func (c *VolumeAPIClient) VolumeInspectWithRaw(context.Context, string) (volume.Volume, []byte, error) {
+	return volume.Volume{}, nil, nil
+}
+
+// VolumeList is a mock implementation of Docker's client.VolumeAPIClient.VolumeList()
+func (c *VolumeAPIClient) VolumeList(context.Context, volume.ListOptions) (volume.ListResponse, error) {
+	if c.ListResp == nil {
+		return volume.ListResponse{}, Err
+	}
+	return *c.ListResp, nil
+}
+
+// VolumeRemove is a mock implementation of Docker's client.VolumeAPIClient.VolumeRemove()
+func (c *VolumeAPIClient) VolumeRemove(context.Context, string, bool) error {
+	return c.RemoveErr
+}
+
+// VolumesPrune is a mock implementation of Docker's client.VolumeAPIClient.VolumesPrune()
+//
+// TODO: properly implement
+func (c *VolumeAPIClient) VolumesPrune(context.Context, filters.Args) (volume.PruneReport, error) {
+	return volume.PruneReport{}, nil
+}
+
+// VolumeUpdate is a mock implementation of Docker's client.VolumeAPIClient.VolumeUpdate()
+//
+// TODO: properly implement
+func (c *VolumeAPIClient) VolumeUpdate(context.Context, string, swarm.Version, volume.UpdateOptions) error {
+	return nil
+}
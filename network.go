@@ -0,0 +1,59 @@
+package dktest
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/network"
+)
+
+const (
+	networkNamePrefix = "dktest_"
+	networkDriver     = "bridge"
+)
+
+func genNetworkName() string { return networkNamePrefix + randString(10) }
+
+// createNetwork creates an isolated user-defined bridge network and returns the network's name. The cleanup timeout is
+// used to remove the network if the process is interrupted. keep specifies that the network may be kept on failure.
+func createNetwork(ctx context.Context, lgr Logger, dc NetworkAPIClient, cleanupTimeout time.Duration,
+	keep bool) (string, error) {
+	name := genNetworkName()
+	labels := resourceLabels(lgr)
+	if keep {
+		labels[KeepOnFailureLabel] = "true"
+	}
+	resp, err := dc.NetworkCreate(ctx, name, network.CreateOptions{
+		Driver: networkDriver,
+		Labels: labels,
+	})
+	if err != nil {
+		return "", err
+	}
+	lgr.Log("Created network:", name, "ID:", resp.ID)
+	live.addNetwork(liveNetwork{lgr: lgr, dc: dc, name: name, cleanupTimeout: cleanupTimeout})
+	if resp.Warning != "" {
+		lgr.Log("Network create warning:", resp.Warning)
+	}
+	return name, nil
+}
+
+func removeNetwork(ctx context.Context, lgr Logger, dc NetworkAPIClient, name string) {
+	live.remove(name)
+	if err := dc.NetworkRemove(ctx, name); err != nil {
+		lgr.Log("Error removing network:", name, "error:", err)
+		return
+	}
+	lgr.Log("Removed network:", name)
+}
+
+// networkingConfig gets the networking config used to attach a container to the given network.
+// The container is not attached to any user-defined network if no network name is given.
+func networkingConfig(netName string, opts Options) *network.NetworkingConfig {
+	if netName == "" || opts.Network == nil {
+		return &network.NetworkingConfig{}
+	}
+	return &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+		netName: {Aliases: opts.Network.Aliases},
+	}}
+}
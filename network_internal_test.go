@@ -0,0 +1,77 @@
+package dktest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateNetwork(t *testing.T) {
+	testCases := []struct {
+		name      string
+		client    mockdockerclient.NetworkAPIClient
+		expectErr bool
+	}{
+		{name: "success", client: mockdockerclient.NetworkAPIClient{
+			CreateResp: &network.CreateResponse{ID: "networkID"}}, expectErr: false},
+		{name: "success - with warning", client: mockdockerclient.NetworkAPIClient{
+			CreateResp: &network.CreateResponse{ID: "networkID", Warning: "warning"}}, expectErr: false},
+		{name: "create error", client: mockdockerclient.NetworkAPIClient{}, expectErr: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			name, err := createNetwork(ctx, t, &client, DefaultCleanupTimeout, false)
+			testErr(t, err, tc.expectErr)
+			if !tc.expectErr && name == "" {
+				t.Error("Expected a network name")
+			}
+		})
+	}
+}
+
+func TestRemoveNetwork(t *testing.T) {
+	testCases := []struct {
+		name   string
+		client mockdockerclient.NetworkAPIClient
+	}{
+		{name: "success", client: mockdockerclient.NetworkAPIClient{}},
+		{name: "remove error", client: mockdockerclient.NetworkAPIClient{RemoveErr: mockdockerclient.Err}},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			removeNetwork(ctx, t, &client, "dktest_network")
+		})
+	}
+}
+
+func TestNetworkingConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		netName  string
+		opts     Options
+		expected *network.NetworkingConfig
+	}{
+		{name: "no network", opts: Options{}, expected: &network.NetworkingConfig{}},
+		{name: "no network name", opts: Options{Network: &NetworkOptions{}}, expected: &network.NetworkingConfig{}},
+		{name: "network not enabled", netName: "net", opts: Options{}, expected: &network.NetworkingConfig{}},
+		{name: "network", netName: "net", opts: Options{Network: &NetworkOptions{Aliases: []string{"db"}}},
+			expected: &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+				"net": {Aliases: []string{"db"}},
+			}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, networkingConfig(tc.netName, tc.opts))
+		})
+	}
+}
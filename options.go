@@ -2,23 +2,43 @@ package dktest
 
 import (
 	"context"
+	"io"
 	"time"
 
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/go-connections/nat"
 )
 
 // Options contains the configurable options for running tests in the docker image
 type Options struct {
-	// PullTimeout is the timeout used when pulling images
+	// PullTimeout is the timeout used when pulling or loading images
 	PullTimeout time.Duration
-	// PullRegistryAuth is the base64 encoded credentials for the registry
+	// BuildTimeout is the timeout used when building images. See Build
+	BuildTimeout time.Duration
+	// PullRegistryAuth is the base64 encoded credentials for the registry.
+	// If not specified, the credentials are resolved from the Docker CLI's config. e.g. ~/.docker/config.json
 	PullRegistryAuth string
+	// PullPolicy specifies when the image is pulled. Defaults to PullAlways
+	PullPolicy PullPolicy
+	// DisablePullCache specifies that the image should be pulled even if it has already been pulled by this process.
+	// By default, concurrent pulls of the same image and platform are deduplicated and successful pulls are cached
+	// for the life of the process.
+	DisablePullCache bool
+	// PullProgress is called with the progress of the image pull. Changes to a layer's status are reported
+	// immediately while the progress of a layer's download or extraction is throttled. If not specified, the progress
+	// is logged. PullProgress may be called concurrently when multiple images are pulled, such as by RunGroup.
+	PullProgress func(PullProgress)
 	// Timeout is the timeout used when starting a container and checking if it's ready
 	Timeout time.Duration
 	// ReadyTimeout is the timeout used for each container ready check.
 	// e.g. each invocation of the ReadyFunc
 	ReadyTimeout time.Duration
+	// ReadyInterval is the interval between container ready checks.
+	// The first ready check is run as soon as the container has started.
+	ReadyInterval time.Duration
+	// ReadyBackoff increases the interval between container ready checks after each check
+	ReadyBackoff *Backoff
 	// CleanupTimeout is the timeout used when stopping and removing a container
 	CleanupTimeout time.Duration
 	// CleanupImage specifies whether or not the image should be removed after the test run.
@@ -37,21 +57,84 @@ type Options struct {
 	Volumes      []string
 	Mounts       []mount.Mount
 	Hostname     string
-	// Platform specifies the platform of the docker image that is pulled.
+	// Platform specifies the platform of the docker image that is pulled. e.g. "linux/arm64"
+	// With PullIfNotPresent or PullNever, an image that's present for a different platform isn't used.
 	Platform     string
 	ExposedPorts nat.PortSet
+	// Network specifies that the container should be attached to an isolated user-defined bridge network,
+	// which is created before the container is started and removed after the container is stopped.
+	// Containers in the same RunGroup share a network and can reach each other by their aliases.
+	Network *NetworkOptions
+	// Client is the Docker client used to run the container. If no client is specified, a new client is created
+	// using the environment's Docker configuration. e.g. DOCKER_HOST
+	// The client is owned by the caller and is not closed by dktest.
+	Client Client
+	// DockerAPIVersion pins the Docker API version used by the client created by dktest.
+	// If not specified, the DKTEST_DOCKER_API_VERSION environment variable is used.
+	// Otherwise, the API version is negotiated with the Docker daemon.
+	DockerAPIVersion string
+	// WaitForHealthy specifies that the container is only ready once its healthcheck reports that it's healthy.
+	// The healthcheck is either declared by the Docker image's HEALTHCHECK instruction or specified by Healthcheck.
+	// If a ReadyFunc is also specified, the container is ready once it's healthy and the ReadyFunc returns true.
+	// Waiting for the container to be ready fails immediately if the container becomes unhealthy or exits.
+	WaitForHealthy bool
+	// Healthcheck defines or overrides the container's healthcheck
+	Healthcheck *container.HealthConfig
+	// Files are copied into the container after it's created and before it's started.
+	// e.g. SQL scripts for the postgres image's /docker-entrypoint-initdb.d directory
+	Files []File
+	// Build specifies that the image should be built instead of pulled. The built image is uniquely tagged and the
+	// image name passed to Run is used as the built image's repository. e.g. "myservice" is built as
+	// "myservice:dktest_<random>". Specify CleanupImage to remove the built image after the test run.
+	Build *BuildOptions
+	// ImageArchive is the path of an image archive created by `docker save`. The images in the archive are loaded
+	// instead of pulled and the archive must contain the image passed to Run. Ignored if Build is specified.
+	ImageArchive string
+	// ImageArchiveReader is similar to ImageArchive, but reads the image archive from the reader.
+	// The reader is consumed, so it can only be used once.
+	ImageArchiveReader io.Reader
+	// Reuse specifies that a running container created with the same image and options should be reused instead of
+	// creating a new container. Reusable containers are kept running after the test run so that they can be reused by
+	// later test runs. Set the DKTEST_REUSE environment variable to false to switch off reuse. e.g. in CI
+	// Reuse isn't supported with Network or Build.
+	Reuse bool
+	// KeepOnFailure specifies that the container should be kept when the test fails or the container fails to start,
+	// so that the container can be debugged. The commands to inspect and clean up the container are logged.
+	// Set the DKTEST_KEEP_ON_FAILURE environment variable to true to keep containers for all tests.
+	KeepOnFailure bool
+	// Logger logs the lifecycle of containers started by Start. If not specified, nothing is logged.
+	// Run and RunContext use their testing.T or logger instead.
+	Logger Logger
+	// StreamLogs specifies that the container's stdout and stderr logs should be logged line by line as they're
+	// written, from when the container is started until the container is stopped. Each line is prefixed with the
+	// container's name and the stream. e.g. "[dktest_abc stdout]"
+	// Unlike LogStdout and LogStderr, the logs are available while the test is running. e.g. when the test hangs
+	StreamLogs bool
+}
+
+// NetworkOptions contains the configurable options for the network a container is attached to
+type NetworkOptions struct {
+	// Aliases are the DNS names the container can be reached by from other containers on the network.
+	// Containers started with RunGroup are always reachable by their ContainerSpec's Name.
+	Aliases []string
 }
 
 func (o *Options) init() {
 	if o.PullTimeout <= 0 {
 		o.PullTimeout = DefaultPullTimeout
 	}
+	if o.BuildTimeout <= 0 {
+		o.BuildTimeout = DefaultBuildTimeout
+	}
 	if o.Timeout <= 0 {
 		o.Timeout = DefaultTimeout
 	}
 	if o.ReadyTimeout <= 0 {
 		o.ReadyTimeout = DefaultReadyTimeout
 	}
+	if o.ReadyInterval <= 0 {
+		o.ReadyInterval = DefaultReadyInterval
+	}
 	if o.CleanupTimeout <= 0 {
 		o.CleanupTimeout = DefaultCleanupTimeout
 	}
@@ -65,9 +148,12 @@ func (o *Options) volumes() map[string]struct{} {
 	return volumes
 }
 
-func (o *Options) env() []string {
-	env := make([]string, 0, len(o.Env))
-	for k, v := range o.Env {
+func (o *Options) env() []string { return envList(o.Env) }
+
+// envList converts the map of environment variables into a list of KEY=VALUE strings
+func envList(m map[string]string) []string {
+	env := make([]string, 0, len(m))
+	for k, v := range m {
 		env = append(env, k+"="+v)
 	}
 	return env
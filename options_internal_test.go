@@ -20,22 +20,28 @@ func TestOptionsInit(t *testing.T) {
 		{name: "default timeouts used", opts: Options{},
 			expected: Options{
 				PullTimeout:    DefaultPullTimeout,
+				BuildTimeout:   DefaultBuildTimeout,
 				Timeout:        DefaultTimeout,
 				ReadyTimeout:   DefaultReadyTimeout,
+				ReadyInterval:  DefaultReadyInterval,
 				CleanupTimeout: DefaultCleanupTimeout,
 			},
 		},
 		{name: "default timeouts not used",
 			opts: Options{
 				PullTimeout:    timeout,
+				BuildTimeout:   timeout,
 				Timeout:        timeout,
 				ReadyTimeout:   timeout,
+				ReadyInterval:  timeout,
 				CleanupTimeout: timeout,
 			},
 			expected: Options{
 				PullTimeout:    timeout,
+				BuildTimeout:   timeout,
 				Timeout:        timeout,
 				ReadyTimeout:   timeout,
+				ReadyInterval:  timeout,
 				CleanupTimeout: timeout,
 			},
 		},
@@ -0,0 +1,87 @@
+package dktest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-units"
+)
+
+// pullProgressInterval is the minimum interval between progress reports for a layer whose status hasn't changed
+const pullProgressInterval = 2 * time.Second
+
+// PullProgress is a progress event for an image being pulled
+type PullProgress struct {
+	// Image is the name of the image being pulled
+	Image string
+	// Layer is the ID of the layer the event is for. Empty for events about the image as a whole.
+	Layer string
+	// Status is the status of the layer or image. e.g. "Downloading", "Extracting", or "Pull complete"
+	Status string
+	// Current is the number of bytes processed for the status
+	Current int64
+	// Total is the total number of bytes to process for the status. Zero if the total is unknown.
+	Total int64
+}
+
+func (p PullProgress) String() string {
+	s := p.Status
+	if p.Layer != "" {
+		s = p.Layer + ": " + s
+	}
+	switch {
+	case p.Total > 0:
+		s += fmt.Sprintf(" %s/%s", units.HumanSize(float64(p.Current)), units.HumanSize(float64(p.Total)))
+	case p.Current > 0:
+		s += " " + units.HumanSize(float64(p.Current))
+	}
+	return s
+}
+
+// pullProgressReporter reports the progress of an image pull. Status changes are reported immediately while
+// progress updates for a layer with an unchanged status are throttled.
+type pullProgressReporter struct {
+	imgName  string
+	interval time.Duration
+	report   func(PullProgress)
+	now      func() time.Time
+	// statuses and reported are the last reported status and time for each layer
+	statuses map[string]string
+	reported map[string]time.Time
+}
+
+// newPullProgressReporter creates a pullProgressReporter that reports to the callback or logs the progress if there's
+// no callback
+func newPullProgressReporter(lgr Logger, imgName string, callback func(PullProgress)) *pullProgressReporter {
+	report := callback
+	if report == nil {
+		report = func(p PullProgress) { lgr.Log("Image pull progress:", p.Image, p.String()) }
+	}
+	return &pullProgressReporter{
+		imgName:  imgName,
+		interval: pullProgressInterval,
+		report:   report,
+		now:      time.Now,
+		statuses: make(map[string]string),
+		reported: make(map[string]time.Time),
+	}
+}
+
+func (r *pullProgressReporter) handle(msg jsonmessage.JSONMessage) {
+	if msg.Status == "" {
+		return
+	}
+	now := r.now()
+	if msg.Status == r.statuses[msg.ID] && now.Sub(r.reported[msg.ID]) < r.interval {
+		return
+	}
+	r.statuses[msg.ID] = msg.Status
+	r.reported[msg.ID] = now
+
+	p := PullProgress{Image: r.imgName, Layer: msg.ID, Status: msg.Status}
+	if msg.Progress != nil {
+		p.Current, p.Total = msg.Progress.Current, msg.Progress.Total
+	}
+	r.report(p)
+}
@@ -0,0 +1,72 @@
+package dktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullProgressString(t *testing.T) {
+	testCases := []struct {
+		name     string
+		progress PullProgress
+		expected string
+	}{
+		{name: "image", progress: PullProgress{Status: "Digest: sha256:0123"}, expected: "Digest: sha256:0123"},
+		{name: "layer", progress: PullProgress{Layer: "0123456789ab", Status: "Pull complete"},
+			expected: "0123456789ab: Pull complete"},
+		{name: "layer with total", progress: PullProgress{Layer: "0123456789ab", Status: "Downloading",
+			Current: 1000, Total: 2000}, expected: "0123456789ab: Downloading 1kB/2kB"},
+		{name: "layer without total", progress: PullProgress{Layer: "0123456789ab", Status: "Extracting",
+			Current: 1000}, expected: "0123456789ab: Extracting 1kB"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.progress.String())
+		})
+	}
+}
+
+func TestPullProgressReporter(t *testing.T) {
+	var reported []PullProgress
+	r := newPullProgressReporter(t, imageName, func(p PullProgress) { reported = append(reported, p) })
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	downloading := func(id string, current int64) jsonmessage.JSONMessage {
+		return jsonmessage.JSONMessage{ID: id, Status: "Downloading",
+			Progress: &jsonmessage.JSONProgress{Current: current, Total: 100}}
+	}
+
+	r.handle(jsonmessage.JSONMessage{ID: "16", Status: "Pulling from library/postgres"})
+	r.handle(downloading("layer1", 10))
+	// throttled
+	r.handle(downloading("layer1", 20))
+	// other layers aren't throttled by layer1
+	r.handle(downloading("layer2", 10))
+	now = now.Add(pullProgressInterval)
+	r.handle(downloading("layer1", 30))
+	// status changes aren't throttled
+	r.handle(jsonmessage.JSONMessage{ID: "layer1", Status: "Download complete"})
+	// messages without a status are ignored
+	r.handle(jsonmessage.JSONMessage{Stream: "ignored"})
+
+	expected := []PullProgress{
+		{Image: imageName, Layer: "16", Status: "Pulling from library/postgres"},
+		{Image: imageName, Layer: "layer1", Status: "Downloading", Current: 10, Total: 100},
+		{Image: imageName, Layer: "layer2", Status: "Downloading", Current: 10, Total: 100},
+		{Image: imageName, Layer: "layer1", Status: "Downloading", Current: 30, Total: 100},
+		{Image: imageName, Layer: "layer1", Status: "Download complete"},
+	}
+	assert.Equal(t, expected, reported)
+}
+
+func TestPullProgressReporterLogs(t *testing.T) {
+	lgr := &recordingLogger{}
+	r := newPullProgressReporter(lgr, imageName, nil)
+	r.handle(jsonmessage.JSONMessage{ID: "layer1", Status: "Pull complete"})
+	assert.Equal(t, [][]interface{}{{"Image pull progress:", imageName, "layer1: Pull complete"}}, lgr.logs)
+}
@@ -0,0 +1,112 @@
+package dktest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// defaultPullCoordinator is shared by every test in the process
+var defaultPullCoordinator = newPullCoordinator()
+
+type pullKey struct {
+	daemon   any
+	imgName  string
+	platform string
+}
+
+// daemonKey identifies the Docker daemon used by the client, since images pulled by one daemon aren't available to
+// other daemons. Clients that can't be identified aren't coordinated.
+func daemonKey(dc ImageAPIClient) (any, bool) {
+	if d, ok := dc.(interface{ DaemonHost() string }); ok {
+		return d.DaemonHost(), true
+	}
+	if dc == nil || !reflect.TypeOf(dc).Comparable() {
+		return nil, false
+	}
+	return dc, true
+}
+
+type pullCall struct {
+	done chan struct{}
+	err  error
+}
+
+// pullCoordinator deduplicates concurrent pulls of the same image and platform by the same Docker daemon so that a
+// single pull serves every waiter. Successful pulls are cached for the life of the process.
+type pullCoordinator struct {
+	mu     sync.Mutex
+	calls  map[pullKey]*pullCall
+	pulled map[pullKey]struct{}
+}
+
+func newPullCoordinator() *pullCoordinator {
+	return &pullCoordinator{calls: make(map[pullKey]*pullCall), pulled: make(map[pullKey]struct{})}
+}
+
+// pull runs the pull func unless the image has already been pulled or is currently being pulled, in which case the
+// in-flight pull is waited on instead. If the in-flight pull is canceled by its caller's context, the pull is retried
+// by one of the waiters.
+func (pc *pullCoordinator) pull(ctx context.Context, lgr Logger, key pullKey,
+	pullFunc func(context.Context) error) error {
+	for {
+		pc.mu.Lock()
+		if _, ok := pc.pulled[key]; ok {
+			pc.mu.Unlock()
+			lgr.Log("Image already pulled:", key.imgName)
+			return nil
+		}
+		call, ok := pc.calls[key]
+		if !ok {
+			call = &pullCall{done: make(chan struct{})}
+			pc.calls[key] = call
+			pc.mu.Unlock()
+			return pc.run(ctx, key, call, pullFunc)
+		}
+		pc.mu.Unlock()
+
+		lgr.Log("Waiting for image pull:", key.imgName)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-call.done:
+		}
+		if call.err == nil {
+			return nil
+		}
+		if ctx.Err() == nil && (errors.Is(call.err, context.Canceled) ||
+			errors.Is(call.err, context.DeadlineExceeded)) {
+			continue
+		}
+		return call.err
+	}
+}
+
+func (pc *pullCoordinator) run(ctx context.Context, key pullKey, call *pullCall,
+	pullFunc func(context.Context) error) error {
+	// Waiters are released even if the pull func panics
+	call.err = errPullAborted
+	defer func() {
+		pc.mu.Lock()
+		delete(pc.calls, key)
+		if call.err == nil {
+			pc.pulled[key] = struct{}{}
+		}
+		pc.mu.Unlock()
+		close(call.done)
+	}()
+	call.err = pullFunc(ctx)
+	return call.err
+}
+
+// forget removes the image from the cache of pulled images for every platform. e.g. when the image is removed
+func (pc *pullCoordinator) forget(imgName string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for key := range pc.pulled {
+		if key.imgName == imgName {
+			delete(pc.pulled, key)
+		}
+	}
+}
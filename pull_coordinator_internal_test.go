@@ -0,0 +1,152 @@
+package dktest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dhui/dktest/mockdockerclient"
+)
+
+func TestPullCoordinatorPull(t *testing.T) {
+	key := pullKey{imgName: imageName}
+
+	t.Run("concurrent pulls", func(t *testing.T) {
+		pc := newPullCoordinator()
+		var pulls atomic.Int32
+		release := make(chan struct{})
+		pullFunc := func(context.Context) error {
+			pulls.Add(1)
+			<-release
+			return nil
+		}
+
+		const n = 10
+		errs := make(chan error, n)
+		var wg sync.WaitGroup
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- pc.pull(context.Background(), t, key, pullFunc)
+			}()
+		}
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			testErr(t, err, false)
+		}
+		if p := pulls.Load(); p != 1 {
+			t.Error("Expected 1 pull but got:", p)
+		}
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		pc := newPullCoordinator()
+		pulls := 0
+		pullFunc := func(context.Context) error {
+			pulls++
+			return nil
+		}
+		testErr(t, pc.pull(context.Background(), t, key, pullFunc), false)
+		testErr(t, pc.pull(context.Background(), t, key, pullFunc), false)
+		testErr(t, pc.pull(context.Background(), t, pullKey{imgName: imageName, platform: "linux/arm64"}, pullFunc),
+			false)
+		if pulls != 2 {
+			t.Error("Expected 2 pulls but got:", pulls)
+		}
+
+		pc.forget(imageName)
+		testErr(t, pc.pull(context.Background(), t, key, pullFunc), false)
+		if pulls != 3 {
+			t.Error("Expected 3 pulls but got:", pulls)
+		}
+	})
+
+	t.Run("errors aren't cached", func(t *testing.T) {
+		pc := newPullCoordinator()
+		pulls := 0
+		pullFunc := func(context.Context) error {
+			pulls++
+			return mockdockerclient.Err
+		}
+		testErr(t, pc.pull(context.Background(), t, key, pullFunc), true)
+		testErr(t, pc.pull(context.Background(), t, key, pullFunc), true)
+		if pulls != 2 {
+			t.Error("Expected 2 pulls but got:", pulls)
+		}
+	})
+
+	t.Run("waiter retries canceled pull", func(t *testing.T) {
+		pc := newPullCoordinator()
+		started := make(chan struct{})
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		firstErr := make(chan error, 1)
+		go func() {
+			firstErr <- pc.pull(ctx, t, key, func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		}()
+		<-started
+
+		secondErr := make(chan error, 1)
+		go func() {
+			secondErr <- pc.pull(context.Background(), t, key, func(context.Context) error { return nil })
+		}()
+		cancelFunc()
+
+		if err := <-firstErr; !errors.Is(err, context.Canceled) {
+			t.Error("Expected context canceled error but got:", err)
+		}
+		testErr(t, <-secondErr, false)
+	})
+
+	t.Run("waiter context canceled", func(t *testing.T) {
+		pc := newPullCoordinator()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		go pc.pull(context.Background(), t, key, func(context.Context) error { // nolint:errcheck
+			close(started)
+			<-release
+			return nil
+		})
+		<-started
+
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		cancelFunc()
+		if err := pc.pull(ctx, t, key, func(context.Context) error { return nil }); !errors.Is(err,
+			context.Canceled) {
+			t.Error("Expected context canceled error but got:", err)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		pc := newPullCoordinator()
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			pc.pull(context.Background(), t, key, func(context.Context) error { panic("pull panic") }) // nolint:errcheck
+		}()
+		testErr(t, pc.pull(context.Background(), t, key, func(context.Context) error { return nil }), false)
+	})
+}
+
+func TestDaemonKey(t *testing.T) {
+	client := &mockdockerclient.ImageAPIClient{}
+	if key, ok := daemonKey(client); !ok || key != client {
+		t.Error("Expected the client to be the daemon key but got:", key, ok)
+	}
+	if _, ok := daemonKey(nil); ok {
+		t.Error("Expected no daemon key for a nil client")
+	}
+}
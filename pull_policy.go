@@ -0,0 +1,96 @@
+package dktest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+// PullPolicy specifies when an image is pulled
+type PullPolicy int
+
+const (
+	// PullAlways always pulls the image. This is the default PullPolicy
+	PullAlways PullPolicy = iota
+	// PullIfNotPresent only pulls the image if it's not present locally
+	PullIfNotPresent
+	// PullNever never pulls the image, so the image must be present locally
+	PullNever
+)
+
+func (p PullPolicy) String() string {
+	switch p {
+	case PullAlways:
+		return "Always"
+	case PullIfNotPresent:
+		return "IfNotPresent"
+	case PullNever:
+		return "Never"
+	default:
+		return fmt.Sprintf("PullPolicy(%d)", int(p))
+	}
+}
+
+// platformMatches checks if the image is for the platform. e.g. "linux/arm64/v8"
+// Only the specified parts of the platform are compared and the variant is only compared if the image has a variant.
+func platformMatches(img image.InspectResponse, platform string) bool {
+	if platform == "" {
+		return true
+	}
+	imgPlatform := []string{img.Os, img.Architecture, img.Variant}
+	for i, part := range strings.SplitN(platform, "/", len(imgPlatform)) {
+		if i == len(imgPlatform)-1 && img.Variant == "" {
+			continue
+		}
+		if !strings.EqualFold(part, imgPlatform[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// imagePresent checks if the image is present locally for the platform
+func imagePresent(ctx context.Context, lgr Logger, dc ImageAPIClient, imgName, platform string) bool {
+	img, err := dc.ImageInspect(ctx, imgName)
+	if err != nil {
+		lgr.Log("Image is not present:", imgName, "error:", err)
+		return false
+	}
+	if !platformMatches(img, platform) {
+		lgr.Log("Image is present for a different platform:", imgName, "platform:", img.Os+"/"+img.Architecture,
+			"expected platform:", platform)
+		return false
+	}
+	lgr.Log("Image is present:", imgName)
+	return true
+}
+
+// ensureImage makes sure the image is present locally by pulling it according to the PullPolicy
+func ensureImage(ctx context.Context, lgr Logger, dc ImageAPIClient, imgName string, opts Options) error {
+	switch opts.PullPolicy {
+	case PullAlways:
+	case PullIfNotPresent:
+		if imagePresent(ctx, lgr, dc, imgName, opts.Platform) {
+			return nil
+		}
+	case PullNever:
+		if imagePresent(ctx, lgr, dc, imgName, opts.Platform) {
+			return nil
+		}
+		return fmt.Errorf("%w: %v pull policy: %v", errImageNotPresent, imgName, opts.PullPolicy)
+	default:
+		return fmt.Errorf("%w: %v", errInvalidPullPolicy, opts.PullPolicy)
+	}
+	pullFunc := func(ctx context.Context) error {
+		return pullImage(ctx, lgr, dc, registryAuth(ctx, lgr, imgName, opts), imgName, opts.Platform,
+			opts.PullProgress)
+	}
+	daemon, ok := daemonKey(dc)
+	if opts.DisablePullCache || !ok {
+		return pullFunc(ctx)
+	}
+	key := pullKey{daemon: daemon, imgName: imgName, platform: opts.Platform}
+	return defaultPullCoordinator.pull(ctx, lgr, key, pullFunc)
+}
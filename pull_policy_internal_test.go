@@ -0,0 +1,115 @@
+package dktest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/image"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureImage(t *testing.T) {
+	successPullResp := mockdockerclient.MockReadCloser{MockReader: mockdockerclient.MockReader{Err: io.EOF}}
+	successInspectResp := &image.InspectResponse{}
+	armInspectResp := &image.InspectResponse{Os: "linux", Architecture: "arm64", Variant: "v8"}
+
+	testCases := []struct {
+		name        string
+		client      mockdockerclient.ImageAPIClient
+		pullPolicy  PullPolicy
+		platform    string
+		expectedErr error
+		expectErr   bool
+	}{
+		{name: "always", client: mockdockerclient.ImageAPIClient{PullResp: successPullResp,
+			InspectResp: successInspectResp}, pullPolicy: PullAlways, expectErr: false},
+		{name: "always - pull error", client: mockdockerclient.ImageAPIClient{InspectResp: successInspectResp},
+			pullPolicy: PullAlways, expectErr: true},
+		{name: "if not present - present", client: mockdockerclient.ImageAPIClient{InspectResp: successInspectResp},
+			pullPolicy: PullIfNotPresent, expectErr: false},
+		{name: "if not present - not present", client: mockdockerclient.ImageAPIClient{PullResp: successPullResp,
+			InspectErr: mockdockerclient.Err}, pullPolicy: PullIfNotPresent, expectErr: false},
+		{name: "if not present - pull error", client: mockdockerclient.ImageAPIClient{
+			InspectErr: mockdockerclient.Err}, pullPolicy: PullIfNotPresent, expectErr: true},
+		{name: "if not present - present for platform", client: mockdockerclient.ImageAPIClient{
+			InspectResp: armInspectResp}, pullPolicy: PullIfNotPresent, platform: "linux/arm64", expectErr: false},
+		{name: "if not present - present for other platform", client: mockdockerclient.ImageAPIClient{
+			PullResp: successPullResp, InspectResp: armInspectResp}, pullPolicy: PullIfNotPresent,
+			platform: "linux/amd64", expectErr: false},
+		{name: "if not present - present for other platform - pull error", client: mockdockerclient.ImageAPIClient{
+			InspectResp: armInspectResp}, pullPolicy: PullIfNotPresent, platform: "linux/amd64", expectErr: true},
+		{name: "never - present for other platform", client: mockdockerclient.ImageAPIClient{
+			InspectResp: armInspectResp}, pullPolicy: PullNever, platform: "linux/amd64",
+			expectedErr: errImageNotPresent, expectErr: true},
+		{name: "never - present", client: mockdockerclient.ImageAPIClient{InspectResp: successInspectResp},
+			pullPolicy: PullNever, expectErr: false},
+		{name: "never - not present", client: mockdockerclient.ImageAPIClient{PullResp: successPullResp,
+			InspectErr: mockdockerclient.Err}, pullPolicy: PullNever, expectedErr: errImageNotPresent, expectErr: true},
+		{name: "invalid", client: mockdockerclient.ImageAPIClient{PullResp: successPullResp,
+			InspectResp: successInspectResp}, pullPolicy: PullPolicy(-1), expectedErr: errInvalidPullPolicy,
+			expectErr: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			err := ensureImage(ctx, t, &client, imageName, Options{PullPolicy: tc.pullPolicy, Platform: tc.platform})
+			testErr(t, err, tc.expectErr)
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error: %v but got: %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestPlatformMatches(t *testing.T) {
+	img := image.InspectResponse{Os: "linux", Architecture: "arm64", Variant: "v8"}
+	noVariant := image.InspectResponse{Os: "linux", Architecture: "amd64"}
+
+	testCases := []struct {
+		name     string
+		img      image.InspectResponse
+		platform string
+		expected bool
+	}{
+		{name: "no platform", img: img, platform: "", expected: true},
+		{name: "os", img: img, platform: "linux", expected: true},
+		{name: "os and arch", img: img, platform: "linux/arm64", expected: true},
+		{name: "os, arch, and variant", img: img, platform: "linux/arm64/v8", expected: true},
+		{name: "case insensitive", img: img, platform: "Linux/ARM64", expected: true},
+		{name: "other os", img: img, platform: "windows/arm64", expected: false},
+		{name: "other arch", img: img, platform: "linux/amd64", expected: false},
+		{name: "other variant", img: img, platform: "linux/arm64/v7", expected: false},
+		{name: "image without variant", img: noVariant, platform: "linux/amd64/v2", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, platformMatches(tc.img, tc.platform))
+		})
+	}
+}
+
+func TestPullPolicyString(t *testing.T) {
+	testCases := []struct {
+		pullPolicy PullPolicy
+		expected   string
+	}{
+		{pullPolicy: PullAlways, expected: "Always"},
+		{pullPolicy: PullIfNotPresent, expected: "IfNotPresent"},
+		{pullPolicy: PullNever, expected: "Never"},
+		{pullPolicy: PullPolicy(5), expected: "PullPolicy(5)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			if s := tc.pullPolicy.String(); s != tc.expected {
+				t.Errorf("%q != %q", s, tc.expected)
+			}
+		})
+	}
+}
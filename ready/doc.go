@@ -0,0 +1,3 @@
+// Package ready provides common functions for checking if a container is ready.
+// Each function returns a func that can be used as a [github.com/dhui/dktest.Options] ReadyFunc.
+package ready
@@ -0,0 +1,99 @@
+package ready
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/dhui/dktest"
+)
+
+// TCP checks if a TCP connection can be made to the published/bound/mapped host port for the given container port
+func TCP(port uint16) func(context.Context, dktest.ContainerInfo) bool {
+	return func(ctx context.Context, c dktest.ContainerInfo) bool {
+		ip, hostPort, err := c.Port(port)
+		if err != nil {
+			return false
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, hostPort))
+		if err != nil {
+			return false
+		}
+		return conn.Close() == nil
+	}
+}
+
+// HTTP checks if an HTTP GET request to the given path on the published/bound/mapped host port for the given container
+// port responds with the expected status code
+func HTTP(port uint16, path string, expectedStatus int) func(context.Context, dktest.ContainerInfo) bool {
+	return func(ctx context.Context, c dktest.ContainerInfo) bool {
+		ip, hostPort, err := c.Port(port)
+		if err != nil {
+			return false
+		}
+		u := url.URL{Scheme: "http", Host: net.JoinHostPort(ip, hostPort), Path: path}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return false
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close() // nolint:errcheck
+		return resp.StatusCode == expectedStatus
+	}
+}
+
+// LogLine checks if the container's stdout and stderr logs have at least the given number of lines matching the
+// regular expression. Some images log the same line multiple times while starting up.
+// e.g. postgres logs "database system is ready to accept connections" before and after running init scripts
+func LogLine(re *regexp.Regexp, occurrences int) func(context.Context, dktest.ContainerInfo) bool {
+	return func(ctx context.Context, c dktest.ContainerInfo) bool {
+		stdout, stderr, err := c.Logs(ctx)
+		if err != nil {
+			return false
+		}
+		return countMatchingLines(re, stdout)+countMatchingLines(re, stderr) >= occurrences
+	}
+}
+
+func countMatchingLines(re *regexp.Regexp, b []byte) int {
+	n := 0
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if re.Match(line) {
+			n++
+		}
+	}
+	return n
+}
+
+// All checks if all of the given ready funcs are ready. The ready funcs are checked in order.
+func All(readyFuncs ...func(context.Context, dktest.ContainerInfo) bool) func(context.Context,
+	dktest.ContainerInfo) bool {
+	return func(ctx context.Context, c dktest.ContainerInfo) bool {
+		for _, readyFunc := range readyFuncs {
+			if !readyFunc(ctx, c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any checks if any of the given ready funcs are ready. The ready funcs are checked in order.
+func Any(readyFuncs ...func(context.Context, dktest.ContainerInfo) bool) func(context.Context,
+	dktest.ContainerInfo) bool {
+	return func(ctx context.Context, c dktest.ContainerInfo) bool {
+		for _, readyFunc := range readyFuncs {
+			if readyFunc(ctx, c) {
+				return true
+			}
+		}
+		return false
+	}
+}
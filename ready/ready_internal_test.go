@@ -0,0 +1,30 @@
+package ready
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCountMatchingLines(t *testing.T) {
+	re := regexp.MustCompile("ready to accept connections")
+
+	testCases := []struct {
+		name     string
+		logs     string
+		expected int
+	}{
+		{name: "empty", logs: "", expected: 0},
+		{name: "no match", logs: "starting\nstarted\n", expected: 0},
+		{name: "1 match", logs: "starting\ndatabase system is ready to accept connections\n", expected: 1},
+		{name: "2 matches", logs: "ready to accept connections\nshutting down\nready to accept connections",
+			expected: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if n := countMatchingLines(re, []byte(tc.logs)); n != tc.expected {
+				t.Error("Matching lines does not match expected:", n, "!=", tc.expected)
+			}
+		})
+	}
+}
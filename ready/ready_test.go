@@ -0,0 +1,117 @@
+package ready_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+
+	"github.com/dhui/dktest"
+	"github.com/dhui/dktest/ready"
+)
+
+const containerPort = 80
+
+func alwaysReady(context.Context, dktest.ContainerInfo) bool { return true }
+func neverReady(context.Context, dktest.ContainerInfo) bool  { return false }
+
+// containerInfo gets a ContainerInfo with the container port mapped to the given address
+func containerInfo(t *testing.T, addr string) dktest.ContainerInfo {
+	t.Helper()
+	ip, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dktest.ContainerInfo{Ports: nat.PortMap{
+		"80/tcp": []nat.PortBinding{{HostIP: ip, HostPort: port}},
+	}}
+}
+
+func expectReady(t *testing.T, readyFunc func(context.Context, dktest.ContainerInfo) bool, c dktest.ContainerInfo,
+	expected bool) {
+	t.Helper()
+	if ready := readyFunc(context.Background(), c); ready && !expected {
+		t.Error("Expected container to not be ready but it was")
+	} else if !ready && expected {
+		t.Error("Expected container to be ready but it wasn't")
+	}
+}
+
+func TestTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+
+	t.Run("ready", func(t *testing.T) {
+		expectReady(t, ready.TCP(containerPort), containerInfo(t, addr), true)
+	})
+	t.Run("wrong port", func(t *testing.T) {
+		expectReady(t, ready.TCP(containerPort+1), containerInfo(t, addr), false)
+	})
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("not listening", func(t *testing.T) {
+		expectReady(t, ready.TCP(containerPort), containerInfo(t, addr), false)
+	})
+}
+
+func TestHTTP(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+	c := containerInfo(t, s.Listener.Addr().String())
+
+	testCases := []struct {
+		name           string
+		port           uint16
+		path           string
+		expectedStatus int
+		expectReady    bool
+	}{
+		{name: "ready", port: containerPort, path: "/healthz", expectedStatus: http.StatusOK, expectReady: true},
+		{name: "ready - expected status", port: containerPort, path: "/", expectedStatus: http.StatusNotFound,
+			expectReady: true},
+		{name: "unexpected status", port: containerPort, path: "/", expectedStatus: http.StatusOK,
+			expectReady: false},
+		{name: "wrong port", port: containerPort + 1, path: "/healthz", expectedStatus: http.StatusOK,
+			expectReady: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expectReady(t, ready.HTTP(tc.port, tc.path, tc.expectedStatus), c, tc.expectReady)
+		})
+	}
+}
+
+func TestLogLine(t *testing.T) {
+	// ContainerInfo without a Docker client has no logs
+	expectReady(t, ready.LogLine(regexp.MustCompile("ready"), 0), dktest.ContainerInfo{}, false)
+}
+
+func TestAll(t *testing.T) {
+	c := dktest.ContainerInfo{}
+	expectReady(t, ready.All(), c, true)
+	expectReady(t, ready.All(alwaysReady, alwaysReady), c, true)
+	expectReady(t, ready.All(alwaysReady, neverReady), c, false)
+	expectReady(t, ready.All(neverReady, neverReady), c, false)
+}
+
+func TestAny(t *testing.T) {
+	c := dktest.ContainerInfo{}
+	expectReady(t, ready.Any(), c, false)
+	expectReady(t, ready.Any(alwaysReady, alwaysReady), c, true)
+	expectReady(t, ready.Any(neverReady, alwaysReady), c, true)
+	expectReady(t, ready.Any(neverReady, neverReady), c, false)
+}
@@ -0,0 +1,147 @@
+package dktest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+const (
+	// reaperEnvVar switches on the reaper when set to a true value
+	reaperEnvVar = "DKTEST_REAPER"
+	// reaperCmd is the command started as the reaper process. The command is installed from the cmd/dktest package.
+	reaperCmd = "dktest"
+	// reaperKeepCmd is sent to the reaper with the ID or name of a resource that shouldn't be removed
+	reaperKeepCmd = "keep"
+	// reaperTimeout is the timeout used by the reaper to remove the session's resources
+	reaperTimeout = time.Minute
+)
+
+// sessionID identifies the resources created by this process
+var sessionID = randString(16)
+
+// reaper is the connection to the reaper process. The reaper process removes the session's resources once the
+// connection is closed, which happens when this process exits for any reason. e.g. the test binary is killed by
+// go test's -timeout or by SIGKILL
+var reaper struct {
+	once sync.Once
+	mu   sync.Mutex
+	w    io.WriteCloser
+}
+
+// reaperEnabled checks if the reaper should be started. The reaper is opt-in and is switched on by the DKTEST_REAPER
+// env var.
+func reaperEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv(reaperEnvVar))
+	return err == nil && enabled
+}
+
+// startReaper starts the session's reaper process the first time it's called. Only the resources created by the
+// Docker daemon used by the first client are reaped. The reaper connects to the Docker daemon using the environment's
+// Docker configuration, so the reaper isn't started for clients specified by the Options, which may be configured
+// differently. e.g. with TLS settings that aren't in the environment
+func startReaper(lgr Logger, dc Client, opts Options) {
+	if !reaperEnabled() {
+		return
+	}
+	if opts.Client != nil {
+		lgr.Log("Not starting reaper since the Docker client isn't configured by the environment")
+		return
+	}
+	d, ok := dc.(interface{ DaemonHost() string })
+	if !ok {
+		return
+	}
+	reaper.once.Do(func() {
+		w, err := spawnReaper(d.DaemonHost())
+		if err != nil {
+			lgr.Log("Failed to start reaper:", err)
+			return
+		}
+		reaper.mu.Lock()
+		reaper.w = w
+		reaper.mu.Unlock()
+		lgr.Log("Started reaper for session:", sessionID)
+	})
+}
+
+// spawnReaper starts the reaper process, which is the dktest command's reaper subcommand, and returns the connection
+// to the reaper
+func spawnReaper(daemonHost string) (io.WriteCloser, error) {
+	path, err := exec.LookPath(reaperCmd)
+	if err != nil {
+		return nil, fmt.Errorf("error finding the %v command, which is installed with: "+
+			"go install github.com/dhui/dktest/cmd/dktest error: %w", reaperCmd, err)
+	}
+	cmd := exec.Command(path, "reaper", "-session", sessionID) // nolint:gosec
+	cmd.Env = append(os.Environ(), client.EnvOverrideHost+"="+daemonHost)
+	// The reaper is in its own process group so that it outlives signals sent to this process' group. e.g. Ctrl-C
+	cmd.SysProcAttr = reaperSysProcAttr()
+	// The reaper's stdout and stderr are discarded so that the reaper doesn't hold open this process' output,
+	// which go test waits on.
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	// Release the reaper's resources if the reaper exits before this process
+	go cmd.Wait() // nolint:errcheck
+	return w, nil
+}
+
+// reaperKeep tells the reaper not to remove the resources with the given IDs or names
+func reaperKeep(lgr Logger, ids ...string) {
+	reaper.mu.Lock()
+	defer reaper.mu.Unlock()
+	if reaper.w == nil {
+		return
+	}
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if _, err := fmt.Fprintln(reaper.w, reaperKeepCmd, id); err != nil {
+			lgr.Log("Failed to tell reaper to keep:", id, "error:", err)
+		}
+	}
+}
+
+// readReaperCommands reads the commands sent to the reaper until the connection is closed and returns the IDs and
+// names of the resources to keep
+func readReaperCommands(r io.Reader) map[string]struct{} {
+	keep := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if id, ok := strings.CutPrefix(scanner.Text(), reaperKeepCmd+" "); ok {
+			keep[id] = struct{}{}
+		}
+	}
+	return keep
+}
+
+// Reap waits for the connection to the session's process, r, to close and then removes the session's resources that
+// the session didn't keep. Reap is run by the dktest command's reaper subcommand, which is started by the session when
+// the reaper is switched on by the DKTEST_REAPER env var.
+func Reap(ctx context.Context, lgr Logger, dc Client, session string, r io.Reader) error {
+	keep := readReaperCommands(r)
+
+	ctx, cancelFunc := context.WithTimeout(ctx, reaperTimeout)
+	defer cancelFunc()
+	return reap(ctx, lgr, dc, session, keep)
+}
+
+// reap removes the containers, networks, and volumes created by the session that aren't kept
+func reap(ctx context.Context, lgr Logger, dc Client, session string, keep map[string]struct{}) error {
+	return removeResources(ctx, lgr, dc, Filter{Session: session}, keep)
+}
@@ -0,0 +1,138 @@
+package dktest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaperEnabled(t *testing.T) {
+	testCases := []struct {
+		envVar   string
+		expected bool
+	}{
+		{envVar: "", expected: false},
+		{envVar: "true", expected: true},
+		{envVar: "1", expected: true},
+		{envVar: "invalid", expected: false},
+		{envVar: "false", expected: false},
+		{envVar: "0", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.envVar, func(t *testing.T) {
+			t.Setenv(reaperEnvVar, tc.envVar)
+			assert.Equal(t, tc.expected, reaperEnabled())
+		})
+	}
+}
+
+func TestReadReaperCommands(t *testing.T) {
+	keep := readReaperCommands(strings.NewReader("keep containerID\nunknown command\nkeep dktest_network\n"))
+	assert.Equal(t, map[string]struct{}{"containerID": {}, "dktest_network": {}}, keep)
+	assert.Empty(t, readReaperCommands(strings.NewReader("")))
+}
+
+func TestReap(t *testing.T) {
+	labels := map[string]string{Label: "true", SessionLabel: sessionID}
+	containers := []container.Summary{
+		{ID: "reapedID", Names: []string{"/dktest_reaped"}, Labels: labels},
+		{ID: "keptByIDID", Names: []string{"/dktest_keptByID"}, Labels: labels},
+		{ID: "keptByNameID", Names: []string{"/dktest_keptByName"}, Labels: labels},
+	}
+	networks := []network.Summary{{ID: "reapedNetworkID", Name: "dktest_reaped", Labels: labels},
+		{ID: "keptNetworkID", Name: "dktest_kept", Labels: labels}}
+	volumes := &volume.ListResponse{Volumes: []*volume.Volume{{Name: "reaped", Labels: labels}, nil}}
+	keep := map[string]struct{}{"keptByIDID": {}, "dktest_keptByName": {}, "dktest_kept": {}}
+
+	testCases := []struct {
+		name      string
+		client    mockdockerclient.Client
+		reaped    []string
+		expectErr bool
+	}{
+		{name: "success", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{ListResp: containers},
+			NetworkAPIClient:   mockdockerclient.NetworkAPIClient{ListResp: networks},
+			VolumeAPIClient:    mockdockerclient.VolumeAPIClient{ListResp: volumes},
+		}, reaped: []string{"Removed container:", "Removed network:", "Removed volume:"}, expectErr: false},
+		{name: "list errors", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{ListErr: mockdockerclient.Err},
+		}, expectErr: true},
+		{name: "remove errors", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{ListResp: containers,
+				RemoveErr: mockdockerclient.Err},
+			NetworkAPIClient: mockdockerclient.NetworkAPIClient{ListResp: networks,
+				RemoveErr: mockdockerclient.Err},
+			VolumeAPIClient: mockdockerclient.VolumeAPIClient{ListResp: volumes, RemoveErr: mockdockerclient.Err},
+		}, expectErr: true},
+		{name: "container list error", client: mockdockerclient.Client{
+			ContainerAPIClient: mockdockerclient.ContainerAPIClient{ListErr: mockdockerclient.Err},
+			NetworkAPIClient:   mockdockerclient.NetworkAPIClient{ListResp: networks},
+			VolumeAPIClient:    mockdockerclient.VolumeAPIClient{ListResp: volumes},
+		}, reaped: []string{"Removed network:", "Removed volume:"}, expectErr: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			lgr := &recordingLogger{}
+			err := reap(ctx, lgr, &client, sessionID, keep)
+			testErr(t, err, tc.expectErr)
+			var reaped []string
+			for _, args := range lgr.logs {
+				reaped = append(reaped, args[0].(string))
+				assert.Contains(t, []interface{}{"reapedID", "dktest_reaped", "reaped"}, args[1])
+			}
+			assert.Equal(t, tc.reaped, reaped)
+		})
+	}
+}
+
+func TestStartReaperNotStarted(t *testing.T) {
+	t.Setenv(reaperEnvVar, "true")
+	dc, err := client.NewClientWithOpts(client.WithHost("unix://" + t.TempDir() + "/docker.sock"))
+	if err != nil {
+		t.Fatal("Error creating Docker client:", err)
+	}
+	defer dc.Close() // nolint:errcheck
+
+	testCases := []struct {
+		name           string
+		client         Client
+		opts           Options
+		expectedLogged bool
+	}{
+		// Mock clients aren't connected to a Docker daemon
+		{name: "mock client", client: &mockdockerclient.Client{}, opts: Options{}, expectedLogged: false},
+		// Clients specified by the Options may not be reachable using the environment's Docker configuration
+		{name: "specified client", client: dc, opts: Options{Client: dc}, expectedLogged: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lgr := &recordingLogger{}
+			startReaper(lgr, tc.client, tc.opts)
+			assert.Equal(t, tc.expectedLogged,
+				lgr.logged("Not starting reaper since the Docker client isn't configured by the environment"))
+			reaper.mu.Lock()
+			defer reaper.mu.Unlock()
+			assert.Nil(t, reaper.w)
+		})
+	}
+}
+
+func TestSpawnReaperMissingCommand(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	w, err := spawnReaper("unix://" + t.TempDir() + "/docker.sock")
+	testErr(t, err, true)
+	assert.Nil(t, w)
+}
@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package dktest
+
+import "syscall"
+
+func reaperSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
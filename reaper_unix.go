@@ -0,0 +1,9 @@
+//go:build unix
+
+package dktest
+
+import "syscall"
+
+func reaperSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
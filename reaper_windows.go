@@ -0,0 +1,9 @@
+//go:build windows
+
+package dktest
+
+import "syscall"
+
+func reaperSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,174 @@
+package dktest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+)
+
+const (
+	// reuseEnvVar switches off container reuse when set to a false value. e.g. DKTEST_REUSE=false in CI
+	reuseEnvVar = "DKTEST_REUSE"
+	// reuseContainerNamePrefix is the prefix of the deterministic names of reusable containers
+	reuseContainerNamePrefix = "dktest_reuse_"
+	// reuseHashLen is the length of the configuration hash used in the names of reusable containers
+	reuseHashLen = 16
+)
+
+// reuseConfig is the configuration of a container that determines if the container can be reused
+type reuseConfig struct {
+	Image        string
+	Platform     string
+	Env          []string
+	Entrypoint   []string
+	Cmd          []string
+	PortBindings nat.PortMap
+	ExposedPorts nat.PortSet
+	ShmSize      int64
+	Volumes      []string
+	Mounts       []mount.Mount
+	Hostname     string
+	Healthcheck  *container.HealthConfig
+	// Files is the hash of the files copied into the container
+	Files string
+}
+
+// reuseEnabled checks if the container should be reused. Reuse is switched off by the DKTEST_REUSE env var.
+func reuseEnabled(opts Options) bool {
+	if !opts.Reuse {
+		return false
+	}
+	if enabled, err := strconv.ParseBool(os.Getenv(reuseEnvVar)); err == nil && !enabled {
+		return false
+	}
+	return true
+}
+
+// reuseHash hashes the image and the options used to create the container
+func reuseHash(imgName string, opts Options) (string, error) {
+	env := opts.env()
+	slices.Sort(env)
+	cfg := reuseConfig{
+		Image:        imgName,
+		Platform:     opts.Platform,
+		Env:          env,
+		Entrypoint:   opts.Entrypoint,
+		Cmd:          opts.Cmd,
+		PortBindings: opts.PortBindings,
+		ExposedPorts: opts.ExposedPorts,
+		ShmSize:      opts.ShmSize,
+		Volumes:      opts.Volumes,
+		Mounts:       opts.Mounts,
+		Hostname:     opts.Hostname,
+		Healthcheck:  opts.Healthcheck,
+	}
+	if len(opts.Files) > 0 {
+		archive, err := filesTar(opts.Files)
+		if err != nil {
+			return "", err
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, archive); err != nil {
+			return "", err
+		}
+		cfg.Files = hex.EncodeToString(h.Sum(nil))
+	}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func reuseContainerName(hash string) string {
+	return reuseContainerNamePrefix + hash[:reuseHashLen]
+}
+
+// findReusableContainer finds the running container with the configuration hash. Exited containers with the
+// configuration hash are removed so that a new container can be created with the same name. Containers that haven't
+// been started yet are skipped since they're being started by another test or process.
+func findReusableContainer(ctx context.Context, lgr Logger, dc ContainerAPIClient, imgName,
+	hash string) (ContainerInfo, bool, error) {
+	containers, err := dc.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", ReuseLabel+"="+hash)),
+	})
+	if err != nil {
+		return ContainerInfo{}, false, err
+	}
+
+	for _, summary := range containers {
+		c := ContainerInfo{ID: summary.ID, Name: reuseContainerName(hash), ImageName: imgName, client: dc}
+		switch summary.State {
+		case container.StateRunning:
+		case container.StateExited, container.StateDead:
+			lgr.Log("Removing stopped reusable container:", c.String())
+			if err := dc.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+				return ContainerInfo{}, false, err
+			}
+			continue
+		default:
+			continue
+		}
+
+		inspectResp, err := dc.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return ContainerInfo{}, false, err
+		}
+		if inspectResp.NetworkSettings != nil {
+			c.Ports = inspectResp.NetworkSettings.Ports
+		}
+		lgr.Log("Found reusable container:", c.String())
+		return c, true, nil
+	}
+
+	return ContainerInfo{}, false, nil
+}
+
+// reuseContainer reuses the running container with the configuration hash if it's ready.
+// If there's no reusable container, an empty ContainerInfo is returned unless wait is specified, in which case
+// reuseContainer waits for the reusable container being created by another test or process to start.
+// The returned ContainerInfo is marked as reused even if the container isn't ready, so that the shared container isn't
+// stopped by the caller.
+func reuseContainer(ctx context.Context, lgr Logger, dc ContainerAPIClient, imgName, hash string,
+	opts Options, wait bool) (ContainerInfo, error) {
+	runCtx, runTimeoutCancelFunc := context.WithTimeout(ctx, opts.Timeout)
+	defer runTimeoutCancelFunc()
+
+	c, ok, err := findReusableContainer(runCtx, lgr, dc, imgName, hash)
+	for err == nil && !ok && wait {
+		select {
+		case <-runCtx.Done():
+			return ContainerInfo{}, fmt.Errorf("error waiting for reusable container: %v error: %w",
+				reuseContainerName(hash), runCtx.Err())
+		case <-time.After(opts.ReadyInterval):
+		}
+		c, ok, err = findReusableContainer(runCtx, lgr, dc, imgName, hash)
+	}
+	if err != nil {
+		return ContainerInfo{}, fmt.Errorf("error finding reusable container: %w", err)
+	} else if !ok {
+		return ContainerInfo{}, nil
+	}
+	c.reused = true
+	if err := waitContainerReady(runCtx, lgr, dc, c, opts); err != nil {
+		return c, fmt.Errorf("%w: %v", err, c.String())
+	}
+	lgr.Log("Reusing container:", c.String())
+
+	return c, nil
+}
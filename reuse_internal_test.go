@@ -0,0 +1,256 @@
+package dktest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReuseEnabled(t *testing.T) {
+	testCases := []struct {
+		name     string
+		reuse    bool
+		envVar   string
+		expected bool
+	}{
+		{name: "not reused", reuse: false, expected: false},
+		{name: "not reused - env var true", reuse: false, envVar: "true", expected: false},
+		{name: "reused", reuse: true, expected: true},
+		{name: "reused - env var true", reuse: true, envVar: "true", expected: true},
+		{name: "reused - env var false", reuse: true, envVar: "false", expected: false},
+		{name: "reused - env var 0", reuse: true, envVar: "0", expected: false},
+		{name: "reused - env var invalid", reuse: true, envVar: "invalid", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(reuseEnvVar, tc.envVar)
+			assert.Equal(t, tc.expected, reuseEnabled(Options{Reuse: tc.reuse}))
+		})
+	}
+}
+
+func TestReuseHash(t *testing.T) {
+	hash := func(imgName string, opts Options) string {
+		t.Helper()
+		h, err := reuseHash(imgName, opts)
+		if err != nil {
+			t.Fatal("Error hashing:", err)
+		}
+		return h
+	}
+
+	opts := Options{Env: map[string]string{"A": "1", "B": "2", "C": "3"}, Cmd: []string{"serve"},
+		Files: []File{{ContainerPath: "/seed.sql", Content: []byte("SELECT 1;")}}}
+	h := hash(imageName, opts)
+	assert.Len(t, h, 64)
+	for range 10 {
+		assert.Equal(t, h, hash(imageName, opts), "hash isn't deterministic")
+	}
+	// Options that don't affect the container don't affect the hash
+	assert.Equal(t, h, hash(imageName, Options{Env: opts.Env, Cmd: opts.Cmd, Files: opts.Files, Reuse: true,
+		Timeout: time.Minute, ReadyFunc: alwaysReady}))
+
+	assert.NotEqual(t, h, hash("otherImage", opts))
+	assert.NotEqual(t, h, hash(imageName, Options{Env: opts.Env, Files: opts.Files}))
+	assert.NotEqual(t, h, hash(imageName, Options{Env: map[string]string{"A": "1"}, Cmd: opts.Cmd,
+		Files: opts.Files}))
+	assert.NotEqual(t, h, hash(imageName, Options{Env: opts.Env, Cmd: opts.Cmd,
+		Files: []File{{ContainerPath: "/seed.sql", Content: []byte("SELECT 2;")}}}))
+
+	if _, err := reuseHash(imageName, Options{Files: []File{{ContainerPath: "relative"}}}); err == nil {
+		t.Error("Expected an error hashing invalid files")
+	}
+}
+
+func TestFindReusableContainer(t *testing.T) {
+	hash := "0123456789abcdef0123456789abcdef"
+	running := container.Summary{ID: "runningID", State: container.StateRunning}
+	stopped := container.Summary{ID: "stoppedID", State: container.StateExited}
+	successInspectResp := &container.InspectResponse{}
+
+	testCases := []struct {
+		name       string
+		client     mockdockerclient.ContainerAPIClient
+		expectedID string
+		expectErr  bool
+	}{
+		{name: "list error", client: mockdockerclient.ContainerAPIClient{ListErr: mockdockerclient.Err},
+			expectErr: true},
+		{name: "no containers", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{}},
+			expectErr: false},
+		{name: "running", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{running},
+			InspectResp: successInspectResp}, expectedID: "runningID", expectErr: false},
+		{name: "inspect error", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{running}},
+			expectErr: true},
+		{name: "stopped", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{stopped}},
+			expectErr: false},
+		{name: "stopped and running", client: mockdockerclient.ContainerAPIClient{
+			ListResp: []container.Summary{stopped, running}, InspectResp: successInspectResp},
+			expectedID: "runningID", expectErr: false},
+		{name: "remove error", client: mockdockerclient.ContainerAPIClient{
+			ListResp: []container.Summary{stopped}, RemoveErr: mockdockerclient.Err}, expectErr: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := tc.client
+			c, ok, err := findReusableContainer(ctx, t, &client, imageName, hash)
+			testErr(t, err, tc.expectErr)
+			assert.Equal(t, tc.expectedID != "", ok)
+			assert.Equal(t, tc.expectedID, c.ID)
+			if ok {
+				assert.Equal(t, "dktest_reuse_0123456789abcdef", c.Name)
+			}
+		})
+	}
+}
+
+func TestRunContextReuse(t *testing.T) {
+	successCreateResp := &container.CreateResponse{ID: "containerID"}
+	running := container.Summary{ID: "runningID", State: container.StateRunning}
+
+	testCases := []struct {
+		name       string
+		client     mockdockerclient.ContainerAPIClient
+		opts       Options
+		expectedID string
+		kept       bool
+		expectErr  bool
+	}{
+		{name: "reused", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{running},
+			InspectResp: &container.InspectResponse{}}, opts: Options{Reuse: true}, expectedID: "runningID",
+			kept: true, expectErr: false},
+		{name: "created", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{},
+			CreateResp: successCreateResp}, opts: Options{Reuse: true}, expectedID: "containerID", kept: true,
+			expectErr: false},
+		{name: "not ready", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{running},
+			InspectResp: &container.InspectResponse{}}, opts: Options{Reuse: true, ReadyFunc: neverReady,
+			Timeout: 100 * time.Millisecond}, kept: true, expectErr: true},
+		{name: "list error", client: mockdockerclient.ContainerAPIClient{CreateResp: successCreateResp,
+			ListErr: mockdockerclient.Err},
+			opts: Options{Reuse: true}, kept: false, expectErr: true},
+		{name: "unsupported", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{running}},
+			opts: Options{Reuse: true, Network: &NetworkOptions{}}, kept: false, expectErr: true},
+		{name: "not reusable", client: mockdockerclient.ContainerAPIClient{ListResp: []container.Summary{running},
+			CreateResp: successCreateResp}, opts: Options{}, expectedID: "containerID", kept: false,
+			expectErr: false},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockdockerclient.Client{
+				ContainerAPIClient: tc.client,
+				ImageAPIClient:     mockdockerclient.ImageAPIClient{InspectResp: &image.InspectResponse{}},
+			}
+			opts := tc.opts
+			opts.Client = client
+			opts.PullPolicy = PullNever
+
+			lgr := &recordingLogger{}
+			var id string
+			err := RunContext(ctx, lgr, imageName, opts, func(c ContainerInfo) error {
+				id = c.ID
+				return nil
+			})
+			testErr(t, err, tc.expectErr)
+			assert.Equal(t, tc.expectedID, id)
+			assert.Equal(t, tc.kept, lgr.logged("Keeping reusable container:"))
+		})
+	}
+}
+
+// sharedContainerClient is a mock client whose ContainerList responses change between calls, e.g. when a reusable
+// container is created by another process. Stopped and removed containers are recorded.
+type sharedContainerClient struct {
+	*mockdockerclient.Client
+	mu        sync.Mutex
+	listResps [][]container.Summary
+	stopped   []string
+	removed   []string
+}
+
+func (c *sharedContainerClient) ContainerList(context.Context, container.ListOptions) ([]container.Summary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp := c.listResps[0]
+	if len(c.listResps) > 1 {
+		c.listResps = c.listResps[1:]
+	}
+	return resp, nil
+}
+
+func (c *sharedContainerClient) ContainerStop(_ context.Context, id string, _ container.StopOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = append(c.stopped, id)
+	return nil
+}
+
+func (c *sharedContainerClient) ContainerRemove(_ context.Context, id string, _ container.RemoveOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removed = append(c.removed, id)
+	return nil
+}
+
+func TestRunContextReuseSharedContainer(t *testing.T) {
+	created := container.Summary{ID: "runningID", State: container.StateCreated}
+	running := container.Summary{ID: "runningID", State: container.StateRunning}
+	conflictErr := fmt.Errorf("container name is already in use: %w", cerrdefs.ErrConflict)
+
+	testCases := []struct {
+		name       string
+		client     mockdockerclient.ContainerAPIClient
+		listResps  [][]container.Summary
+		readyFunc  func(context.Context, ContainerInfo) bool
+		expectedID string
+		expectErr  bool
+	}{
+		{name: "created concurrently", client: mockdockerclient.ContainerAPIClient{CreateErr: conflictErr,
+			InspectResp: &container.InspectResponse{}}, listResps: [][]container.Summary{{}, {created}, {running}},
+			expectedID: "runningID", expectErr: false},
+		{name: "created concurrently - never started", client: mockdockerclient.ContainerAPIClient{
+			CreateErr: conflictErr}, listResps: [][]container.Summary{{created}}, expectErr: true},
+		{name: "not ready", client: mockdockerclient.ContainerAPIClient{InspectResp: &container.InspectResponse{}},
+			listResps: [][]container.Summary{{running}}, readyFunc: neverReady, expectErr: true},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &sharedContainerClient{Client: &mockdockerclient.Client{
+				ContainerAPIClient: tc.client,
+				ImageAPIClient:     mockdockerclient.ImageAPIClient{InspectResp: &image.InspectResponse{}},
+			}, listResps: tc.listResps}
+
+			var id string
+			err := RunContext(ctx, t, imageName, Options{
+				Client:        client,
+				PullPolicy:    PullNever,
+				Reuse:         true,
+				ReadyFunc:     tc.readyFunc,
+				ReadyInterval: 10 * time.Millisecond,
+				Timeout:       200 * time.Millisecond,
+			}, func(c ContainerInfo) error {
+				id = c.ID
+				return nil
+			})
+			testErr(t, err, tc.expectErr)
+			assert.Equal(t, tc.expectedID, id)
+			// The shared container must never be stopped or removed since it's used by other tests or processes
+			assert.Empty(t, client.stopped)
+			assert.Empty(t, client.removed)
+		})
+	}
+}
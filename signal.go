@@ -0,0 +1,139 @@
+package dktest
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// liveContainer is a container that hasn't been stopped yet
+type liveContainer struct {
+	lgr            Logger
+	dc             ContainerAPIClient
+	info           ContainerInfo
+	cleanupTimeout time.Duration
+	logStdout      bool
+	logStderr      bool
+}
+
+// liveNetwork is a network that hasn't been removed yet
+type liveNetwork struct {
+	lgr            Logger
+	dc             NetworkAPIClient
+	name           string
+	cleanupTimeout time.Duration
+}
+
+// liveResources tracks the containers and networks created by dktest that haven't been stopped or removed yet, so
+// that they can be cleaned up when the process is interrupted. Kept and reusable containers aren't tracked.
+type liveResources struct {
+	mu         sync.Mutex
+	containers map[string]liveContainer
+	networks   map[string]liveNetwork
+}
+
+// live is the live resources of this process
+var live = newLiveResources()
+
+var signalCleanupOnce sync.Once
+
+func newLiveResources() *liveResources {
+	return &liveResources{containers: make(map[string]liveContainer), networks: make(map[string]liveNetwork)}
+}
+
+func (r *liveResources) addContainer(c liveContainer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.containers[c.info.ID] = c
+}
+
+// updateContainer updates the info of the container if it's tracked. e.g. once the container's logs are streamed
+func (r *liveResources) updateContainer(info ContainerInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.containers[info.ID]; ok {
+		c.info = info
+		r.containers[info.ID] = c
+	}
+}
+
+func (r *liveResources) addNetwork(n liveNetwork) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.networks[n.name] = n
+}
+
+// remove stops tracking the containers and networks with the given IDs or names
+func (r *liveResources) remove(ids ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		delete(r.containers, id)
+		delete(r.networks, id)
+	}
+}
+
+// cleanup concurrently stops all of the live containers and then removes all of the live networks, since networks
+// can't be removed while containers are attached to them. Each resource is cleaned up within its CleanupTimeout.
+func (r *liveResources) cleanup() {
+	r.mu.Lock()
+	containers, networks := r.containers, r.networks
+	r.containers, r.networks = make(map[string]liveContainer), make(map[string]liveNetwork)
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range containers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancelFunc := context.WithTimeout(context.Background(), c.cleanupTimeout)
+			defer cancelFunc()
+			stopContainer(ctx, c.lgr, c.dc, c.info, c.logStdout, c.logStderr)
+		}()
+	}
+	wg.Wait()
+
+	for _, n := range networks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancelFunc := context.WithTimeout(context.Background(), n.cleanupTimeout)
+			defer cancelFunc()
+			removeNetwork(ctx, n.lgr, n.dc, n.name)
+		}()
+	}
+	wg.Wait()
+}
+
+// InstallSignalCleanup installs a handler for SIGINT and SIGTERM that stops and removes all of the live containers
+// and networks created by dktest before re-raising the signal. Without the handler, interrupting the tests, e.g. with
+// Ctrl-C, exits the process before the containers are cleaned up. Containers kept by KeepOnFailure and reusable
+// containers aren't cleaned up by the handler.
+// InstallSignalCleanup is typically called from TestMain and only installs the handler once, so subsequent calls have
+// no effect.
+func InstallSignalCleanup() {
+	signalCleanupOnce.Do(func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-sigs
+			// Restore the default behavior of the signals before cleaning up, so that a second signal exits the
+			// process immediately and re-raising the signal exits the process the same way it would have without the
+			// handler
+			signal.Reset(os.Interrupt, syscall.SIGTERM)
+			live.cleanup()
+
+			p, err := os.FindProcess(os.Getpid())
+			if err == nil {
+				err = p.Signal(sig)
+			}
+			if err != nil {
+				// e.g. sending signals isn't supported on Windows
+				os.Exit(1)
+			}
+		}()
+	})
+}
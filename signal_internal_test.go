@@ -0,0 +1,109 @@
+package dktest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func isLive(id string) bool {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	_, isContainer := live.containers[id]
+	_, isNetwork := live.networks[id]
+	return isContainer || isNetwork
+}
+
+func TestLiveResourcesTracking(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("container", func(t *testing.T) {
+		client := &mockdockerclient.ContainerAPIClient{
+			CreateResp: &container.CreateResponse{ID: "liveContainerID"}}
+		c, err := runImage(ctx, t, client, "image", "", "", Options{})
+		testErr(t, err, false)
+		assert.True(t, isLive(c.ID), "Expected the created container to be tracked")
+		stopContainer(ctx, t, client, c, false, false)
+		assert.False(t, isLive(c.ID), "Expected the stopped container to not be tracked")
+	})
+
+	t.Run("container with log stream", func(t *testing.T) {
+		r, w := io.Pipe()
+		defer w.Close() // nolint:errcheck
+		client := &mockdockerclient.ContainerAPIClient{
+			CreateResp: &container.CreateResponse{ID: "streamedContainerID"}, Logs: r}
+		c, err := runImage(ctx, t, client, "image", "", "", Options{StreamLogs: true})
+		testErr(t, err, false)
+		live.mu.Lock()
+		tracked := live.containers[c.ID].info.logStream
+		live.mu.Unlock()
+		assert.NotNil(t, tracked, "Expected the tracked container to have the log stream")
+		c.logStream.stop()
+		live.remove(c.ID)
+	})
+
+	t.Run("reusable container", func(t *testing.T) {
+		client := &mockdockerclient.ContainerAPIClient{
+			CreateResp: &container.CreateResponse{ID: "reusableContainerID"}}
+		c, err := runImage(ctx, t, client, "image", "", "0123456789abcdef0123456789abcdef", Options{})
+		testErr(t, err, false)
+		assert.False(t, isLive(c.ID), "Expected the reusable container to not be tracked")
+	})
+
+	t.Run("network", func(t *testing.T) {
+		client := &mockdockerclient.NetworkAPIClient{CreateResp: &network.CreateResponse{ID: "networkID"}}
+		name, err := createNetwork(ctx, t, client, DefaultCleanupTimeout, false)
+		testErr(t, err, false)
+		assert.True(t, isLive(name), "Expected the created network to be tracked")
+		removeNetwork(ctx, t, client, name)
+		assert.False(t, isLive(name), "Expected the removed network to not be tracked")
+	})
+}
+
+func TestLiveResourcesCleanup(t *testing.T) {
+	lgr := &recordingLogger{}
+	c := ContainerInfo{ID: "containerID", Name: "dktest_container"}
+	r := newLiveResources()
+	r.addContainer(liveContainer{lgr: lgr, dc: &mockdockerclient.ContainerAPIClient{}, info: c,
+		cleanupTimeout: DefaultCleanupTimeout})
+	r.addContainer(liveContainer{lgr: lgr, dc: &mockdockerclient.ContainerAPIClient{},
+		info: ContainerInfo{ID: "keptContainerID", Name: "dktest_kept"}, cleanupTimeout: DefaultCleanupTimeout})
+	r.addNetwork(liveNetwork{lgr: lgr, dc: &mockdockerclient.NetworkAPIClient{}, name: "dktest_network",
+		cleanupTimeout: DefaultCleanupTimeout})
+	r.remove("keptContainerID")
+	// The log stream of a container that doesn't stop is stopped once the cleanup timeout is reached
+	logs, w := io.Pipe()
+	defer w.Close() // nolint:errcheck
+	streamed := ContainerInfo{ID: "streamedContainerID", Name: "dktest_streamed"}
+	r.addContainer(liveContainer{lgr: lgr, dc: &mockdockerclient.ContainerAPIClient{}, info: streamed,
+		cleanupTimeout: 10 * time.Millisecond})
+	streamed.logStream = streamLogs(lgr, &mockdockerclient.ContainerAPIClient{Logs: logs}, streamed)
+	r.updateContainer(streamed)
+
+	r.cleanup()
+	select {
+	case <-streamed.logStream.done:
+	default:
+		t.Error("Expected the log stream to be stopped")
+	}
+
+	var stopped, removed []string
+	for _, args := range lgr.logs {
+		switch args[0] {
+		case "Stopped container:":
+			stopped = append(stopped, args[1].(string))
+		case "Removed network:":
+			removed = append(removed, args[1].(string))
+		}
+	}
+	assert.ElementsMatch(t, []string{c.String(), streamed.String()}, stopped)
+	assert.Equal(t, []string{"dktest_network"}, removed)
+	assert.Empty(t, r.containers)
+	assert.Empty(t, r.networks)
+}
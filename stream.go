@@ -0,0 +1,101 @@
+package dktest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// logStream follows a container's logs and logs each line through the Logger until the container stops or the
+// stream is stopped. A nil logStream is valid and does nothing.
+type logStream struct {
+	logs       io.ReadCloser
+	cancelFunc context.CancelFunc
+	done       chan struct{}
+}
+
+// lineLogger is an io.Writer that logs each complete line written to it with the prefix
+type lineLogger struct {
+	lgr    Logger
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf.Write(p)
+	for {
+		i := bytes.IndexByte(l.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := l.buf.Next(i + 1)
+		l.lgr.Log(l.prefix, string(bytes.TrimRight(line, "\r\n")))
+	}
+	return len(p), nil
+}
+
+// flush logs the remaining partial line, if any
+func (l *lineLogger) flush() {
+	if l.buf.Len() > 0 {
+		l.lgr.Log(l.prefix, l.buf.String())
+		l.buf.Reset()
+	}
+}
+
+// streamLogs starts following the container's stdout and stderr logs. Each line is logged prefixed with the
+// container's name and the stream the line was written to. e.g. "[dktest_abc stdout] ready"
+func streamLogs(lgr Logger, dc ContainerAPIClient, c ContainerInfo) *logStream {
+	// The stream outlives the context used to start the container, so it's only cancelled by stop
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	logs, err := dc.ContainerLogs(ctx, c.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+	if err != nil {
+		cancelFunc()
+		lgr.Log("Error streaming container logs:", c.String(), "error:", err)
+		return nil
+	}
+	lgr.Log("Streaming container logs:", c.String())
+
+	s := &logStream{logs: logs, cancelFunc: cancelFunc, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		defer logs.Close() // nolint:errcheck
+		stdout := &lineLogger{lgr: lgr, prefix: fmt.Sprintf("[%s stdout]", c.Name)}
+		stderr := &lineLogger{lgr: lgr, prefix: fmt.Sprintf("[%s stderr]", c.Name)}
+		_, err := stdcopy.StdCopy(stdout, stderr, logs)
+		stdout.flush()
+		stderr.flush()
+		if err != nil && ctx.Err() == nil {
+			lgr.Log("Error streaming container logs:", c.String(), "error:", err)
+		}
+	}()
+	return s
+}
+
+// wait waits for the stream to end, which happens once the container has stopped. If the context is done first, the
+// stream is stopped. The remaining logs are logged before wait returns.
+func (s *logStream) wait(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	select {
+	case <-s.done:
+		s.cancelFunc()
+	case <-ctx.Done():
+		s.stop()
+	}
+}
+
+// stop stops following the logs of a container that's kept running and waits for the stream to end
+func (s *logStream) stop() {
+	if s == nil {
+		return
+	}
+	s.cancelFunc()
+	// Closing the logs unblocks the pending read
+	s.logs.Close() // nolint:errcheck
+	<-s.done
+}
@@ -0,0 +1,97 @@
+package dktest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest/mockdockerclient"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineLogger(t *testing.T) {
+	testCases := []struct {
+		name     string
+		writes   []string
+		expected []string
+	}{
+		{name: "no writes", writes: nil, expected: nil},
+		{name: "one line", writes: []string{"ready\n"}, expected: []string{"ready"}},
+		{name: "multiple lines", writes: []string{"starting\nready\n"}, expected: []string{"starting", "ready"}},
+		{name: "split lines", writes: []string{"sta", "rting\nrea", "dy\n"}, expected: []string{"starting", "ready"}},
+		{name: "crlf", writes: []string{"ready\r\n"}, expected: []string{"ready"}},
+		{name: "empty line", writes: []string{"\n"}, expected: []string{""}},
+		{name: "partial line", writes: []string{"starting\nrea"}, expected: []string{"starting", "rea"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lgr := &recordingLogger{}
+			l := &lineLogger{lgr: lgr, prefix: "[dktest_container stdout]"}
+			for _, w := range tc.writes {
+				n, err := l.Write([]byte(w))
+				testErr(t, err, false)
+				assert.Equal(t, len(w), n)
+			}
+			l.flush()
+
+			var lines []string
+			for _, args := range lgr.logs {
+				assert.Equal(t, "[dktest_container stdout]", args[0])
+				lines = append(lines, args[1].(string))
+			}
+			assert.Equal(t, tc.expected, lines)
+		})
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	var logs bytes.Buffer
+	if _, err := stdcopy.NewStdWriter(&logs, stdcopy.Stdout).Write([]byte("out\n")); err != nil {
+		t.Fatal("Error writing logs:", err)
+	}
+	if _, err := stdcopy.NewStdWriter(&logs, stdcopy.Stderr).Write([]byte("err")); err != nil {
+		t.Fatal("Error writing logs:", err)
+	}
+	c := ContainerInfo{ID: "containerID", Name: "dktest_container"}
+
+	t.Run("success", func(t *testing.T) {
+		lgr := &recordingLogger{}
+		client := &mockdockerclient.ContainerAPIClient{Logs: io.NopCloser(bytes.NewReader(logs.Bytes()))}
+		s := streamLogs(lgr, client, c)
+		if s == nil {
+			t.Fatal("Expected a log stream")
+		}
+		s.wait(context.Background())
+		assert.Contains(t, lgr.logs, []interface{}{"[dktest_container stdout]", "out"})
+		assert.Contains(t, lgr.logs, []interface{}{"[dktest_container stderr]", "err"})
+	})
+
+	t.Run("logs error", func(t *testing.T) {
+		lgr := &recordingLogger{}
+		s := streamLogs(lgr, &mockdockerclient.ContainerAPIClient{}, c)
+		assert.Nil(t, s)
+		assert.True(t, lgr.logged("Error streaming container logs:"))
+		// A nil stream is a no-op
+		s.wait(context.Background())
+		s.stop()
+	})
+
+	t.Run("stop running container", func(t *testing.T) {
+		lgr := &recordingLogger{}
+		r, w := io.Pipe()
+		defer w.Close() // nolint:errcheck
+		s := streamLogs(lgr, &mockdockerclient.ContainerAPIClient{Logs: r}, c)
+		if _, err := stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte("out\n")); err != nil {
+			t.Fatal("Error writing logs:", err)
+		}
+		ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancelFunc()
+		s.wait(ctx)
+		assert.Contains(t, lgr.logs, []interface{}{"[dktest_container stdout]", "out"})
+		assert.False(t, lgr.logged("Error streaming container logs:"))
+	})
+}